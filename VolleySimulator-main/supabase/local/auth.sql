@@ -0,0 +1,29 @@
+-- Stand-ins for the Supabase auth schema, so schema.sql and the migrations
+-- apply to a plain PostgreSQL (the Go API's DATABASE_BACKEND=postgres).
+-- `go-backend migrate -local` applies it before schema.sql. Not for Supabase
+-- projects, which have auth.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE SCHEMA IF NOT EXISTS auth;
+
+-- Users come from the identity provider; insert rows here for local accounts.
+-- The on_auth_user_created trigger of schema.sql creates profile and leaderboard rows.
+CREATE TABLE IF NOT EXISTS auth.users (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email TEXT UNIQUE,
+    raw_user_meta_data JSONB DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Used by the RLS policies. The API connects as the table owner, which
+-- RLS does not apply to, so these only matter for other roles.
+CREATE OR REPLACE FUNCTION auth.uid() RETURNS UUID
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.sub', true), '')::uuid
+$$;
+
+CREATE OR REPLACE FUNCTION auth.role() RETURNS TEXT
+LANGUAGE sql STABLE AS $$
+    SELECT COALESCE(NULLIF(current_setting('request.jwt.claim.role', true), ''), 'service_role')
+$$;
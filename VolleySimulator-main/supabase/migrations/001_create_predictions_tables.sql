@@ -22,21 +22,25 @@ CREATE TABLE IF NOT EXISTS predictions (
 ALTER TABLE predictions ENABLE ROW LEVEL SECURITY;
 
 -- Users can only access their own predictions
+DROP POLICY IF EXISTS "Users can view own predictions" ON predictions;
 CREATE POLICY "Users can view own predictions" ON predictions
     FOR SELECT USING (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can insert own predictions" ON predictions;
 CREATE POLICY "Users can insert own predictions" ON predictions
     FOR INSERT WITH CHECK (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can update own predictions" ON predictions;
 CREATE POLICY "Users can update own predictions" ON predictions
     FOR UPDATE USING (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can delete own predictions" ON predictions;
 CREATE POLICY "Users can delete own predictions" ON predictions
     FOR DELETE USING (auth.uid() = user_id);
 
 -- Index for fast lookups
-CREATE INDEX idx_predictions_user_league ON predictions(user_id, league);
-CREATE INDEX idx_predictions_user_league_group ON predictions(user_id, league, group_name);
+CREATE INDEX IF NOT EXISTS idx_predictions_user_league ON predictions(user_id, league);
+CREATE INDEX IF NOT EXISTS idx_predictions_user_league_group ON predictions(user_id, league, group_name);
 
 -- ============================================
 -- USER STATS TABLE
@@ -64,12 +68,15 @@ CREATE TABLE IF NOT EXISTS user_stats (
 ALTER TABLE user_stats ENABLE ROW LEVEL SECURITY;
 
 -- Users can only access their own stats
+DROP POLICY IF EXISTS "Users can view own stats" ON user_stats;
 CREATE POLICY "Users can view own stats" ON user_stats
     FOR SELECT USING (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can insert own stats" ON user_stats;
 CREATE POLICY "Users can insert own stats" ON user_stats
     FOR INSERT WITH CHECK (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can update own stats" ON user_stats;
 CREATE POLICY "Users can update own stats" ON user_stats
     FOR UPDATE USING (auth.uid() = user_id);
 
@@ -84,10 +91,12 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_predictions_updated_at ON predictions;
 CREATE TRIGGER update_predictions_updated_at
     BEFORE UPDATE ON predictions
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_user_stats_updated_at ON user_stats;
 CREATE TRIGGER update_user_stats_updated_at
     BEFORE UPDATE ON user_stats
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
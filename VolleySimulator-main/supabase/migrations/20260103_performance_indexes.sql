@@ -40,9 +40,18 @@ CREATE INDEX IF NOT EXISTS idx_match_results_league_date ON public.match_results
 -- Index for ranking queries (sorted by points descending)
 CREATE INDEX IF NOT EXISTS idx_leaderboard_points ON public.leaderboard(total_points DESC);
 
--- Index for league-specific leaderboards
-CREATE INDEX IF NOT EXISTS idx_leaderboard_vsl_points ON public.leaderboard(vsl_points DESC NULLS LAST);
-CREATE INDEX IF NOT EXISTS idx_leaderboard_1lig_points ON public.leaderboard(lig1_points DESC NULLS LAST);
+-- Index for league-specific leaderboards, where the per-league columns exist
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = 'public' AND table_name = 'leaderboard' AND column_name = 'vsl_points') THEN
+        CREATE INDEX IF NOT EXISTS idx_leaderboard_vsl_points ON public.leaderboard(vsl_points DESC NULLS LAST);
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = 'public' AND table_name = 'leaderboard' AND column_name = 'lig1_points') THEN
+        CREATE INDEX IF NOT EXISTS idx_leaderboard_1lig_points ON public.leaderboard(lig1_points DESC NULLS LAST);
+    END IF;
+END $$;
 
 -- ============================================================================
 -- USER PROFILES TABLE INDEXES
@@ -58,8 +67,14 @@ CREATE INDEX IF NOT EXISTS idx_user_profiles_level ON public.user_profiles(level
 -- PUSH SUBSCRIPTIONS TABLE INDEXES
 -- ============================================================================
 
--- Index for user lookups
-CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON public.push_subscriptions(user_id);
+-- Index for user lookups (the table is created by 20260103_push_subscriptions.sql,
+-- which sorts after this file)
+DO $$
+BEGIN
+    IF to_regclass('public.push_subscriptions') IS NOT NULL THEN
+        CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON public.push_subscriptions(user_id);
+    END IF;
+END $$;
 
 -- ============================================================================
 -- STATISTICS / ANALYTICS
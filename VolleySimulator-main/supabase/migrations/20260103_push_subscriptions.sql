@@ -16,15 +16,19 @@ CREATE TABLE IF NOT EXISTS push_subscriptions (
 ALTER TABLE push_subscriptions ENABLE ROW LEVEL SECURITY;
 
 -- Users can only manage their own subscriptions
+DROP POLICY IF EXISTS "Users can view own subscriptions" ON push_subscriptions;
 CREATE POLICY "Users can view own subscriptions" ON push_subscriptions
     FOR SELECT USING (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can insert own subscriptions" ON push_subscriptions;
 CREATE POLICY "Users can insert own subscriptions" ON push_subscriptions
     FOR INSERT WITH CHECK (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can update own subscriptions" ON push_subscriptions;
 CREATE POLICY "Users can update own subscriptions" ON push_subscriptions
     FOR UPDATE USING (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can delete own subscriptions" ON push_subscriptions;
 CREATE POLICY "Users can delete own subscriptions" ON push_subscriptions
     FOR DELETE USING (auth.uid() = user_id);
 
@@ -150,17 +150,27 @@ ALTER TABLE public.standings ENABLE ROW LEVEL SECURITY;
 ALTER TABLE public.playoff_matches ENABLE ROW LEVEL SECURITY;
 
 -- Public read access policies
+DROP POLICY IF EXISTS "Public read leagues" ON public.leagues;
 CREATE POLICY "Public read leagues" ON public.leagues FOR SELECT USING (true);
+DROP POLICY IF EXISTS "Public read teams" ON public.teams;
 CREATE POLICY "Public read teams" ON public.teams FOR SELECT USING (true);
+DROP POLICY IF EXISTS "Public read matches" ON public.matches;
 CREATE POLICY "Public read matches" ON public.matches FOR SELECT USING (true);
+DROP POLICY IF EXISTS "Public read standings" ON public.standings;
 CREATE POLICY "Public read standings" ON public.standings FOR SELECT USING (true);
+DROP POLICY IF EXISTS "Public read playoff_matches" ON public.playoff_matches;
 CREATE POLICY "Public read playoff_matches" ON public.playoff_matches FOR SELECT USING (true);
 
 -- Service role write policies
+DROP POLICY IF EXISTS "Service write leagues" ON public.leagues;
 CREATE POLICY "Service write leagues" ON public.leagues FOR ALL USING (auth.role() = 'service_role');
+DROP POLICY IF EXISTS "Service write teams" ON public.teams;
 CREATE POLICY "Service write teams" ON public.teams FOR ALL USING (auth.role() = 'service_role');
+DROP POLICY IF EXISTS "Service write matches" ON public.matches;
 CREATE POLICY "Service write matches" ON public.matches FOR ALL USING (auth.role() = 'service_role');
+DROP POLICY IF EXISTS "Service write standings" ON public.standings;
 CREATE POLICY "Service write standings" ON public.standings FOR ALL USING (auth.role() = 'service_role');
+DROP POLICY IF EXISTS "Service write playoff_matches" ON public.playoff_matches;
 CREATE POLICY "Service write playoff_matches" ON public.playoff_matches FOR ALL USING (auth.role() = 'service_role');
 
 -- ============================================================================
@@ -177,18 +187,23 @@ END;
 $$ language 'plpgsql';
 
 -- Apply triggers
+DROP TRIGGER IF EXISTS update_leagues_updated_at ON public.leagues;
 CREATE TRIGGER update_leagues_updated_at BEFORE UPDATE ON public.leagues
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_teams_updated_at ON public.teams;
 CREATE TRIGGER update_teams_updated_at BEFORE UPDATE ON public.teams
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_matches_updated_at ON public.matches;
 CREATE TRIGGER update_matches_updated_at BEFORE UPDATE ON public.matches
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_standings_updated_at ON public.standings;
 CREATE TRIGGER update_standings_updated_at BEFORE UPDATE ON public.standings
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_playoff_matches_updated_at ON public.playoff_matches;
 CREATE TRIGGER update_playoff_matches_updated_at BEFORE UPDATE ON public.playoff_matches
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
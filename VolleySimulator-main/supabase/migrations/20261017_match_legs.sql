@@ -0,0 +1,4 @@
+-- Leg of a two-legged cup tie, NULL for league matches. The league sync
+-- tells the legs of a pairing apart by it.
+
+ALTER TABLE public.matches ADD COLUMN IF NOT EXISTS leg INTEGER;
@@ -0,0 +1,6 @@
+-- Set-by-set rally scores of verified results, stored like matches.home_sets
+-- and away_sets: [25, 23, 25] and [20, 25, 18]. Empty when only the result
+-- is known.
+
+ALTER TABLE public.match_results ADD COLUMN IF NOT EXISTS home_sets JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE public.match_results ADD COLUMN IF NOT EXISTS away_sets JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -22,12 +22,15 @@ CREATE TABLE IF NOT EXISTS public.user_profiles (
 -- RLS Policies for user_profiles
 ALTER TABLE public.user_profiles ENABLE ROW LEVEL SECURITY;
 
+DROP POLICY IF EXISTS "Users can view all profiles" ON public.user_profiles;
 CREATE POLICY "Users can view all profiles" ON public.user_profiles
     FOR SELECT USING (true);
 
+DROP POLICY IF EXISTS "Users can update own profile" ON public.user_profiles;
 CREATE POLICY "Users can update own profile" ON public.user_profiles
     FOR UPDATE USING (auth.uid() = id);
 
+DROP POLICY IF EXISTS "Users can insert own profile" ON public.user_profiles;
 CREATE POLICY "Users can insert own profile" ON public.user_profiles
     FOR INSERT WITH CHECK (auth.uid() = id);
 
@@ -55,12 +58,15 @@ CREATE TABLE IF NOT EXISTS public.predictions (
 -- RLS Policies for predictions
 ALTER TABLE public.predictions ENABLE ROW LEVEL SECURITY;
 
+DROP POLICY IF EXISTS "Users can view own predictions" ON public.predictions;
 CREATE POLICY "Users can view own predictions" ON public.predictions
     FOR SELECT USING (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can insert own predictions" ON public.predictions;
 CREATE POLICY "Users can insert own predictions" ON public.predictions
     FOR INSERT WITH CHECK (auth.uid() = user_id);
 
+DROP POLICY IF EXISTS "Users can update own predictions" ON public.predictions;
 CREATE POLICY "Users can update own predictions" ON public.predictions
     FOR UPDATE USING (auth.uid() = user_id AND is_scored = FALSE);
 
@@ -83,10 +89,12 @@ CREATE TABLE IF NOT EXISTS public.match_results (
 -- RLS Policies for match_results (public read)
 ALTER TABLE public.match_results ENABLE ROW LEVEL SECURITY;
 
+DROP POLICY IF EXISTS "Anyone can view match results" ON public.match_results;
 CREATE POLICY "Anyone can view match results" ON public.match_results
     FOR SELECT USING (true);
 
 -- Only service role can insert/update results
+DROP POLICY IF EXISTS "Service role can manage results" ON public.match_results;
 CREATE POLICY "Service role can manage results" ON public.match_results
     FOR ALL USING (auth.role() = 'service_role');
 
@@ -111,9 +119,11 @@ CREATE TABLE IF NOT EXISTS public.leaderboard (
 -- RLS Policies for leaderboard (public read)
 ALTER TABLE public.leaderboard ENABLE ROW LEVEL SECURITY;
 
+DROP POLICY IF EXISTS "Anyone can view leaderboard" ON public.leaderboard;
 CREATE POLICY "Anyone can view leaderboard" ON public.leaderboard
     FOR SELECT USING (true);
 
+DROP POLICY IF EXISTS "Users can update own leaderboard entry" ON public.leaderboard;
 CREATE POLICY "Users can update own leaderboard entry" ON public.leaderboard
     FOR UPDATE USING (auth.uid() = user_id);
 
@@ -151,14 +161,17 @@ END;
 $$ LANGUAGE plpgsql;
 
 -- Triggers for updated_at
+DROP TRIGGER IF EXISTS update_user_profiles_updated_at ON public.user_profiles;
 CREATE TRIGGER update_user_profiles_updated_at
     BEFORE UPDATE ON public.user_profiles
     FOR EACH ROW EXECUTE FUNCTION public.update_updated_at();
 
+DROP TRIGGER IF EXISTS update_predictions_updated_at ON public.predictions;
 CREATE TRIGGER update_predictions_updated_at
     BEFORE UPDATE ON public.predictions
     FOR EACH ROW EXECUTE FUNCTION public.update_updated_at();
 
+DROP TRIGGER IF EXISTS update_leaderboard_updated_at ON public.leaderboard;
 CREATE TRIGGER update_leaderboard_updated_at
     BEFORE UPDATE ON public.leaderboard
     FOR EACH ROW EXECUTE FUNCTION public.update_updated_at();
@@ -0,0 +1,487 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/joho/godotenv"
+
+    "go-backend/database"
+    "go-backend/league"
+    "go-backend/scraper"
+    "go-backend/utils"
+)
+
+// Offline tools, run as `./main <command> [flags]` instead of starting the server
+var commands = map[string]func(args []string) error{
+    "fit-elo":    fitEloCommand,
+    "migrate":    migrateCommand,
+    "scrape":     scrapeCommand,
+    "scrape-cev": scrapeCEVCommand,
+    "sync":       syncCommand,
+    "validate":   validateCommand,
+}
+
+func runCommand(args []string) bool {
+    if len(args) == 0 {
+        return false
+    }
+
+    cmd, ok := commands[args[0]]
+    if !ok {
+        names := make([]string, 0, len(commands))
+        for name := range commands {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        fmt.Fprintf(os.Stderr, "unknown command %q, available: %v\n", args[0], names)
+        os.Exit(2)
+    }
+
+    if err := cmd(args[1:]); err != nil {
+        log.Fatalf("%s: %v", args[0], err)
+    }
+    return true
+}
+
+// validateCommand checks league data files, by default every known league file
+func validateCommand(args []string) error {
+    fs := flag.NewFlagSet("validate", flag.ExitOnError)
+    dataDir := fs.String("data", "data", "directory with the league data files")
+    verbose := fs.Bool("v", false, "list every warning")
+    registry := fs.String("leagues", league.DefaultRegistryPath, "league registry")
+    fs.Parse(args)
+
+    if err := league.LoadRegistry(*registry); err != nil {
+        return err
+    }
+
+    files := league.Leagues.Files()
+    if fs.NArg() > 0 {
+        // Explicit files are validated under their file name
+        files = make(map[string]string)
+        for _, path := range fs.Args() {
+            files[filepath.Base(path)] = path
+        }
+        *dataDir = ""
+    }
+
+    ids := make([]string, 0, len(files))
+    for id := range files {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    failed := 0
+    for _, id := range ids {
+        _, report, err := league.LoadFile(filepath.Join(*dataDir, files[id]), id)
+        if report == nil {
+            fmt.Printf("%s: %v\n", id, err)
+            failed++
+            continue
+        }
+
+        status := "ok"
+        if report.HasErrors() {
+            status = "INVALID"
+            failed++
+        }
+        fmt.Printf("%s (%s): %s, %d errors, %d warnings\n", id, report.Flavour, status, len(report.Errors), len(report.Warnings))
+        for _, issue := range report.Errors {
+            fmt.Printf("  error   #%d %s %s\n", issue.Match, issue.MatchNo, issue.Message)
+        }
+        if *verbose {
+            for _, issue := range report.Warnings {
+                fmt.Printf("  warning #%d %s %s\n", issue.Match, issue.MatchNo, issue.Message)
+            }
+        }
+    }
+
+    if failed > 0 {
+        return fmt.Errorf("%d invalid files", failed)
+    }
+    return nil
+}
+
+func fitEloCommand(args []string) error {
+    fs := flag.NewFlagSet("fit-elo", flag.ExitOnError)
+    dataDir := fs.String("data", "data", "directory with the league data files")
+    out := fs.String("out", utils.DefaultEloParamsPath, "where to write the fitted parameters")
+    minMatches := fs.Int("min-matches", 50, "leagues with fewer played matches keep the defaults")
+    holdout := fs.Float64("holdout", 0.2, "share of the season, from its end, kept out of the fit to score it")
+    registry := fs.String("leagues", league.DefaultRegistryPath, "league registry")
+    fs.Parse(args)
+    if *holdout < 0 || *holdout >= 1 {
+        return fmt.Errorf("-holdout must be at least 0 and below 1, got %g", *holdout)
+    }
+
+    if err := league.LoadRegistry(*registry); err != nil {
+        return err
+    }
+
+    if err := utils.LoadTeamRegistry(utils.DefaultTeamRegistryPath); err != nil {
+        log.Printf("Team registry not loaded: %v", err)
+    }
+
+    file := utils.EloParamsFile{
+        GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+        Default:     utils.DefaultEloParams,
+        Leagues:     make(map[string]utils.FittedEloParams),
+    }
+
+    for _, cfg := range league.Leagues.All() {
+        id := cfg.ID
+        if !cfg.IsActive { continue }
+        l, _, err := league.LoadFile(filepath.Join(*dataDir, cfg.File), id)
+        if err != nil {
+            log.Printf("%s: skipped (%v)", id, err)
+            continue
+        }
+        _, matches := l.EloInput()
+
+        _, count := utils.EloLogLoss(matches, utils.DefaultEloParams)
+        if count < *minMatches {
+            log.Printf("%s: skipped (%d played matches, need %d)", id, count, *minMatches)
+            continue
+        }
+
+        fit := utils.FitEloHoldout(matches, utils.DefaultEloGrid, *holdout)
+        params := fit.Params
+        log.Printf("%s: %d matches, log-loss %.4f -> %.4f (k=%.2f sweep=%.3f margin=%.3f scale=%.1f), held-out %d matches %.4f -> %.4f",
+            id, fit.Matches, fit.Baseline, fit.LogLoss, params.K, params.SweepMultiplier, params.MarginMultiplier, params.Scale,
+            fit.HoldoutMatches, fit.HoldoutBaseline, fit.HoldoutLogLoss)
+        if fit.HoldoutMatches > 0 && fit.HoldoutLogLoss >= fit.HoldoutBaseline {
+            log.Printf("%s: keeps the defaults, the fit does not beat them on the held-out matches", id)
+            continue
+        }
+
+        // The held-out tail only judged the fit, the saved parameters learn from every match
+        if fit.HoldoutMatches > 0 {
+            params, fit.LogLoss = utils.FitEloParams(matches, utils.DefaultEloGrid)
+            fit.Baseline, fit.Matches = utils.EloLogLoss(matches, utils.DefaultEloParams)
+            log.Printf("%s: refit on all %d matches, log-loss %.4f -> %.4f (k=%.2f sweep=%.3f margin=%.3f scale=%.1f)",
+                id, fit.Matches, fit.Baseline, fit.LogLoss, params.K, params.SweepMultiplier, params.MarginMultiplier, params.Scale)
+        }
+        if edges := utils.DefaultEloGrid.AtBounds(params); len(edges) > 0 {
+            log.Printf("%s: %s on the edge of the search grid", id, strings.Join(edges, ", "))
+        }
+
+        file.Leagues[id] = utils.FittedEloParams{
+            EloParams:              params,
+            LogLoss:                fit.LogLoss,
+            BaselineLogLoss:        fit.Baseline,
+            Matches:                fit.Matches,
+            HoldoutLogLoss:         fit.HoldoutLogLoss,
+            HoldoutBaselineLogLoss: fit.HoldoutBaseline,
+            HoldoutMatches:         fit.HoldoutMatches,
+        }
+    }
+
+    if err := utils.SaveEloParams(*out, file); err != nil {
+        return err
+    }
+    log.Printf("Wrote %s", *out)
+    return nil
+}
+
+// syncCommand writes the league files into the Supabase league tables. With
+// -dry-run it only prints the changes a run would make.
+func syncCommand(args []string) error {
+    fs := flag.NewFlagSet("sync", flag.ExitOnError)
+    dataDir := fs.String("data", "data", "directory with the league data files")
+    registry := fs.String("leagues", league.DefaultRegistryPath, "league registry")
+    only := fs.String("league", "", "comma separated league ids, default every active league")
+    dryRun := fs.Bool("dry-run", false, "print the changes without writing them")
+    verbose := fs.Bool("v", false, "list every change, not only the totals")
+    fs.Parse(args)
+
+    godotenv.Load()
+    repos, err := database.Open()
+    if err != nil {
+        return err
+    }
+    if err := league.LoadRegistry(*registry); err != nil {
+        return err
+    }
+    if err := utils.LoadTeamRegistry(utils.DefaultTeamRegistryPath); err != nil {
+        log.Printf("Team registry not loaded: %v", err)
+    }
+
+    var configs []league.Config
+    if *only == "" {
+        for _, cfg := range league.Leagues.All() {
+            if cfg.IsActive { configs = append(configs, cfg) }
+        }
+    } else {
+        for _, id := range strings.Split(*only, ",") {
+            cfg, ok := league.Leagues.Get(strings.TrimSpace(id))
+            if !ok {
+                return fmt.Errorf("unknown league %q", id)
+            }
+            configs = append(configs, cfg)
+        }
+    }
+
+    failed := 0
+    for _, cfg := range configs {
+        l, _, err := league.LoadFile(filepath.Join(*dataDir, cfg.File), cfg.ID)
+        if err != nil {
+            // Invalid files are never written, LoadFile fails on validation errors
+            fmt.Printf("%s: skipped, %v\n", cfg.ID, err)
+            failed++
+            continue
+        }
+
+        plan, err := league.Sync(repos.Leagues, cfg, l, *dryRun)
+        if err != nil {
+            fmt.Printf("%s: %v\n", cfg.ID, err)
+            failed++
+        }
+        fmt.Printf("%s: %d inserts, %d updates, %d deletes\n", cfg.ID,
+            plan.Count(league.SyncInsert), plan.Count(league.SyncUpdate), plan.Count(league.SyncDelete))
+        if *dryRun || *verbose {
+            marks := map[string]string{league.SyncInsert: "+", league.SyncUpdate: "~", league.SyncDelete: "-"}
+            for _, ch := range plan.Changes {
+                line := fmt.Sprintf("  %s %-9s %s", marks[ch.Action], ch.Table, ch.Key)
+                if len(ch.Fields) > 0 { line += " (" + strings.Join(ch.Fields, ", ") + ")" }
+                fmt.Println(line)
+            }
+        }
+    }
+
+    if failed > 0 {
+        return fmt.Errorf("%d leagues not synced", failed)
+    }
+    return nil
+}
+
+// migrateCommand applies the SQL files of the supabase directory to
+// DATABASE_URL and records them in schema_migrations. Actions:
+//   up        apply the pending files in order, each in its own transaction (default)
+//   status    list every file as applied, pending or changed since it was applied
+//   baseline  record every file as applied without running it, for databases
+//             set up by hand in the Supabase SQL editor
+func migrateCommand(args []string) error {
+    fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+    dir := fs.String("dir", "../VolleySimulator-main/supabase", "directory with schema.sql and migrations/")
+    local := fs.Bool("local", false, "apply local/auth.sql first, for a plain PostgreSQL without Supabase auth")
+    dryRun := fs.Bool("dry-run", false, "list the pending files without applying them")
+    fs.Parse(args)
+
+    action := "up"
+    if fs.NArg() > 0 { action = fs.Arg(0) }
+    if action != "up" && action != "status" && action != "baseline" {
+        return fmt.Errorf("unknown action %q, use up, status or baseline", action)
+    }
+
+    migrations, err := database.MigrationFiles(*dir, *local)
+    if err != nil {
+        return err
+    }
+
+    godotenv.Load()
+    url := os.Getenv("DATABASE_URL")
+    if url == "" {
+        return fmt.Errorf("DATABASE_URL must be set")
+    }
+    pg, err := database.NewPostgres(url)
+    if err != nil {
+        return err
+    }
+    defer pg.Close()
+
+    applied, err := pg.AppliedMigrations()
+    if err != nil {
+        return err
+    }
+
+    var pending []database.Migration
+    for _, m := range migrations {
+        done, ok := applied[m.Version]
+        switch {
+        case !ok:
+            pending = append(pending, m)
+            if action == "status" { fmt.Printf("  pending  %s\n", m.Version) }
+        case done.Checksum != m.Checksum:
+            // Applied files are never rerun, changes need a new migration
+            if action == "status" { fmt.Printf("  changed  %s (applied %s)\n", m.Version, done.AppliedAt.Format(time.RFC3339)) }
+        default:
+            if action == "status" { fmt.Printf("  applied  %s (%s)\n", m.Version, done.AppliedAt.Format(time.RFC3339)) }
+        }
+    }
+    if action == "status" {
+        fmt.Printf("%d applied, %d pending\n", len(migrations)-len(pending), len(pending))
+        return nil
+    }
+
+    for _, m := range pending {
+        if *dryRun {
+            fmt.Printf("would %s %s\n", map[string]string{"up": "apply", "baseline": "mark"}[action], m.Version)
+            continue
+        }
+        var recorded bool
+        if action == "baseline" {
+            recorded, err = pg.MarkMigration(m)
+        } else {
+            recorded, err = pg.ApplyMigration(m)
+        }
+        if err != nil {
+            return err
+        }
+        if recorded {
+            fmt.Printf("%s %s\n", map[string]string{"up": "applied", "baseline": "marked"}[action], m.Version)
+        }
+    }
+    if len(pending) == 0 { fmt.Println("nothing to apply") }
+    return nil
+}
+
+// scrapeCommand prints what /api/cron/update-results would take from the
+// TVF calendar page, without saving anything. With -file it reads a saved
+// page instead, such as the fixtures in scraper/testdata.
+func scrapeCommand(args []string) error {
+    fs := flag.NewFlagSet("scrape", flag.ExitOnError)
+    dataDir := fs.String("data", "data", "directory with the league data files")
+    file := fs.String("file", "", "saved HTML page to parse instead of fetching")
+    url := fs.String("url", scraper.TVFTakvimURL, "page to fetch")
+    fs.Parse(args)
+
+    if err := league.LoadRegistry(league.DefaultRegistryPath); err != nil {
+        return err
+    }
+    if err := utils.LoadTeamRegistry(utils.DefaultTeamRegistryPath); err != nil {
+        log.Printf("Team registry not loaded: %v", err)
+    }
+    store := league.NewStore(*dataDir, league.Leagues.Files())
+    if err := store.Load(); err != nil {
+        return err
+    }
+
+    var results []scraper.Result
+    var err error
+    if *file != "" {
+        f, openErr := os.Open(*file)
+        if openErr != nil {
+            return openErr
+        }
+        defer f.Close()
+        results, err = scraper.ParseTVF(f)
+    } else {
+        results, err = scraper.FetchTVF(context.Background(), *url)
+    }
+    if err != nil {
+        return err
+    }
+
+    matched, unmatched := scraper.Resolve(results, store.Snapshot().Leagues)
+    for _, m := range matched {
+        played := ""
+        if m.Fixture.IsPlayed { played = fmt.Sprintf(" (file has %s)", m.Fixture.Score()) }
+        if !m.Finished() {
+            played += " in progress"
+        } else if sets := utils.ParseSets(m.SetResults); len(sets) > 0 {
+            if err := utils.CheckSets(sets, m.HomeScore, m.AwayScore); err != nil { played += " sets dropped: " + err.Error() }
+        }
+        fmt.Printf("  %-5s %s %s%s\n", m.League, m.MatchID(), m.Score(), played)
+    }
+    for _, res := range unmatched {
+        if res.League == "" {
+            fmt.Printf("  skip  %s: %s - %s\n", res.Header, res.HomeTeam, res.AwayTeam)
+        } else {
+            fmt.Printf("  ?     %s: %s - %s %s, no fixture\n", res.League, res.HomeTeam, res.AwayTeam, res.Score())
+        }
+    }
+    fmt.Printf("%d results, %d matched\n", len(results), len(matched))
+    return nil
+}
+
+// scrapeCEVCommand rebuilds a CEV competition's data file from its page on
+// cev.eu, or with -file from a saved page. The file is only replaced when the
+// result passes validation.
+func scrapeCEVCommand(args []string) error {
+    fs := flag.NewFlagSet("scrape-cev", flag.ExitOnError)
+    id := fs.String("league", "cev-cl", "competition: cev-cl, cev-cup or cev-challenge")
+    dataDir := fs.String("data", "data", "directory with the league data files")
+    file := fs.String("file", "", "saved HTML page to parse instead of fetching")
+    url := fs.String("url", "", "page to fetch, default the competition's page for the season")
+    season := fs.String("season", "", "season to record, default the one in the current file")
+    dryRun := fs.Bool("dry-run", false, "print the fixtures without writing the file")
+    fs.Parse(args)
+
+    if err := league.LoadRegistry(league.DefaultRegistryPath); err != nil {
+        return err
+    }
+    if err := utils.LoadTeamRegistry(utils.DefaultTeamRegistryPath); err != nil {
+        log.Printf("Team registry not loaded: %v", err)
+    }
+    cfg, ok := league.Leagues.Get(*id)
+    if _, known := scraper.CEVCompetitions[*id]; !ok || !known {
+        return fmt.Errorf("%q is not a CEV competition", *id)
+    }
+    path := filepath.Join(*dataDir, cfg.File)
+    if *season == "" {
+        if current, _, err := league.LoadFile(path, *id); current != nil && err == nil {
+            *season = current.Season
+        }
+    }
+    if *url == "" { *url, _ = scraper.CEVURL(*id, *season) }
+
+    var l *league.League
+    var err error
+    if *file != "" {
+        f, openErr := os.Open(*file)
+        if openErr != nil {
+            return openErr
+        }
+        defer f.Close()
+        l, err = scraper.ParseCEV(*id, f)
+    } else {
+        l, err = scraper.FetchCEV(context.Background(), *id, *url)
+    }
+    if err != nil {
+        return err
+    }
+
+    l.Season = *season
+
+    played, golden := 0, 0
+    for _, f := range l.Fixture {
+        switch {
+        case f.GoldenSet:
+            golden++
+        case f.IsPlayed:
+            played++
+        }
+        if *dryRun {
+            line := fmt.Sprintf("  %-22s %s - %s", f.Round, f.HomeTeam, f.AwayTeam)
+            if f.GroupName != "" { line = fmt.Sprintf("  %-22s %-7s %s - %s", f.Round, f.GroupName, f.HomeTeam, f.AwayTeam) }
+            if f.Leg > 0 { line += fmt.Sprintf(" (leg %d)", f.Leg) }
+            if f.GoldenSet { line += " golden set" }
+            if f.IsPlayed { line += " " + f.Score() + " " + f.SetResults }
+            fmt.Println(line)
+        }
+        if f.IsPlayed && !f.GoldenSet && f.SetResults != "" {
+            if err := utils.CheckSets(utils.ParseSets(f.SetResults), *f.HomeScore, *f.AwayScore); err != nil {
+                fmt.Printf("  warning: %s - %s %s: %v\n", f.HomeTeam, f.AwayTeam, f.SetResults, err)
+            }
+        }
+    }
+    fmt.Printf("%s: %d teams, %d matches, %d played, %d golden sets, stage %s\n",
+        *id, len(l.Teams), len(l.Fixture)-golden, played, golden, l.Stage)
+    if *dryRun {
+        return nil
+    }
+
+    if err := scraper.WriteLeague(path, l); err != nil {
+        return fmt.Errorf("%s not written: %w", path, err)
+    }
+    log.Printf("Wrote %s", path)
+    return nil
+}
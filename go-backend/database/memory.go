@@ -0,0 +1,399 @@
+package database
+
+import (
+    "crypto/rand"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Memory implements the repositories in process for running the API without
+// a Supabase project. Everything is lost on restart.
+type Memory struct {
+    mu          sync.Mutex
+    predictions map[string]*Prediction // by user and match
+    results     map[string]*MatchResult
+    leaderboard map[string]*LeaderboardEntry
+    profiles    map[string]*Profile
+    tables      map[string][]map[string]interface{} // league tables used by sync
+}
+
+func NewMemory() *Memory {
+    return &Memory{
+        predictions: make(map[string]*Prediction),
+        results:     make(map[string]*MatchResult),
+        leaderboard: make(map[string]*LeaderboardEntry),
+        profiles:    make(map[string]*Profile),
+        tables:      make(map[string][]map[string]interface{}),
+    }
+}
+
+func (m *Memory) Repositories() *Repositories {
+    return &Repositories{Predictions: m, Results: m, Leaderboard: m, Profiles: m, Leagues: m}
+}
+
+func newUUID() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    b[6] = b[6]&0x0f | 0x40
+    b[8] = b[8]&0x3f | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func now() string {
+    return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func (m *Memory) ListPredictions(userID string, filter PredictionFilter) ([]Prediction, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    predictions := make([]Prediction, 0)
+    for _, p := range m.predictions {
+        if p.UserID != userID { continue }
+        if filter.League != "" && p.League != filter.League { continue }
+        if filter.Group != "" && p.GroupName != filter.Group { continue }
+        predictions = append(predictions, *p)
+    }
+    sort.Slice(predictions, func(i, j int) bool { return predictions[i].CreatedAt > predictions[j].CreatedAt })
+    if filter.Limit > 0 && len(predictions) > filter.Limit {
+        predictions = predictions[:filter.Limit]
+    }
+    return predictions, nil
+}
+
+func (m *Memory) SavePredictions(predictions []Prediction) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for _, p := range predictions {
+        key := p.UserID + "|" + p.MatchID
+        if existing, ok := m.predictions[key]; ok {
+            p.ID, p.CreatedAt = existing.ID, existing.CreatedAt
+            p.PointsEarned, p.IsScored = existing.PointsEarned, existing.IsScored
+        } else {
+            p.ID, p.CreatedAt = newUUID(), now()
+            p.PointsEarned, p.IsScored = 0, false
+        }
+        p.UpdatedAt = now()
+        m.predictions[key] = &p
+    }
+    return nil
+}
+
+func (m *Memory) DeletePrediction(userID, matchID string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    delete(m.predictions, userID+"|"+matchID)
+    return nil
+}
+
+func (m *Memory) UnscoredPredictions(matchID string) ([]Prediction, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    var predictions []Prediction
+    for _, p := range m.predictions {
+        if p.MatchID == matchID && !p.IsScored {
+            predictions = append(predictions, *p)
+        }
+    }
+    return predictions, nil
+}
+
+func (m *Memory) MatchPredictions(matchID string) ([]Prediction, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    var predictions []Prediction
+    for _, p := range m.predictions {
+        if p.MatchID == matchID {
+            predictions = append(predictions, *p)
+        }
+    }
+    return predictions, nil
+}
+
+func (m *Memory) ScorePrediction(id string, points int) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for _, p := range m.predictions {
+        if p.ID == id {
+            p.PointsEarned, p.IsScored, p.UpdatedAt = points, true, now()
+        }
+    }
+    return nil
+}
+
+func (m *Memory) CountPredictions() (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return len(m.predictions), nil
+}
+
+func (m *Memory) PendingMatchIDs() ([]string, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    seen := make(map[string]bool)
+    ids := make([]string, 0)
+    for _, p := range m.predictions {
+        if !p.IsScored && !seen[p.MatchID] {
+            seen[p.MatchID] = true
+            ids = append(ids, p.MatchID)
+        }
+    }
+    sort.Strings(ids)
+    return ids, nil
+}
+
+func (m *Memory) ScoredPredictions() ([]Prediction, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    predictions := make([]Prediction, 0)
+    for _, p := range m.predictions {
+        if p.IsScored { predictions = append(predictions, *p) }
+    }
+    return predictions, nil
+}
+
+func (m *Memory) SaveResult(r MatchResult) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.saveResult(r)
+    return nil
+}
+
+func (m *Memory) saveResult(r MatchResult) {
+    r.HomeSets, r.AwaySets = r.sets()
+    if existing, ok := m.results[r.MatchID]; ok {
+        r.ID, r.CreatedAt = existing.ID, existing.CreatedAt
+    } else {
+        r.ID, r.CreatedAt = newUUID(), now()
+    }
+    m.results[r.MatchID] = &r
+}
+
+func (m *Memory) SaveScoredResult(r MatchResult, points func(predicted string) int) (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    previous, known := m.results[r.MatchID]
+    rescore := known && previous.ResultScore != r.ResultScore
+    m.saveResult(r)
+
+    scored := 0
+    for _, p := range m.predictions {
+        if p.MatchID != r.MatchID || p.IsScored && !rescore { continue }
+        p.PointsEarned, p.IsScored, p.UpdatedAt = points(p.PredictedScore), true, now()
+        scored++
+    }
+    return scored, nil
+}
+
+func (m *Memory) CountResults() (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return len(m.results), nil
+}
+
+func (m *Memory) Leaderboard(period string, limit int) ([]LeaderboardEntry, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    entries := make([]LeaderboardEntry, 0, len(m.leaderboard))
+    for _, e := range m.leaderboard {
+        entries = append(entries, *e)
+    }
+    sort.SliceStable(entries, func(i, j int) bool { return entries[i].Points(period) > entries[j].Points(period) })
+    if limit > 0 && len(entries) > limit {
+        entries = entries[:limit]
+    }
+    return entries, nil
+}
+
+func (m *Memory) LeaderboardEntry(userID string) (*LeaderboardEntry, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    e, ok := m.leaderboard[userID]
+    if !ok { return nil, nil }
+    entry := *e
+    return &entry, nil
+}
+
+func (m *Memory) LeaderboardRank(period string, points int) (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    rank := 1
+    for _, e := range m.leaderboard {
+        if e.Points(period) > points { rank++ }
+    }
+    return rank, nil
+}
+
+func (m *Memory) SetDisplayName(userID, name string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    if e, ok := m.leaderboard[userID]; ok {
+        e.DisplayName, e.UpdatedAt = &name, now()
+    }
+    return nil
+}
+
+func (m *Memory) SaveLeaderboardStats(entries []LeaderboardEntry) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for _, e := range entries {
+        existing, ok := m.leaderboard[e.UserID]
+        if !ok {
+            existing = &LeaderboardEntry{ID: newUUID(), UserID: e.UserID}
+            m.leaderboard[e.UserID] = existing
+        }
+        e.ID, e.DisplayName, e.UpdatedAt = existing.ID, existing.DisplayName, now()
+        *existing = e
+    }
+    return nil
+}
+
+func (m *Memory) Profile(userID string) (*Profile, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    p, ok := m.profiles[userID]
+    if !ok { return nil, nil }
+    profile := *p
+    return &profile, nil
+}
+
+// UpdateProfile creates the profile on first use, where Supabase creates it
+// with a trigger when the user signs up
+func (m *Memory) UpdateProfile(userID string, update ProfileUpdate) (*Profile, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    p, ok := m.profiles[userID]
+    if !ok {
+        p = &Profile{ID: userID, Level: 1, Achievements: json.RawMessage("[]"), CreatedAt: now()}
+        m.profiles[userID] = p
+    }
+    if update.DisplayName != nil { p.DisplayName = update.DisplayName }
+    if update.AvatarURL != nil { p.AvatarURL = update.AvatarURL }
+    if update.FavoriteTeam != nil { p.FavoriteTeam = update.FavoriteTeam }
+    p.UpdatedAt = now()
+
+    profile := *p
+    return &profile, nil
+}
+
+func (m *Memory) CountProfiles() (int, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return len(m.profiles), nil
+}
+
+func (m *Memory) ListLeagues() ([]LeagueRecord, error) {
+    rows, err := m.Rows("leagues", "", "")
+    if err != nil { return nil, err }
+
+    // Through JSON like a database row, so defaults and types line up
+    var records []LeagueRecord
+    content, _ := json.Marshal(rows)
+    err = json.Unmarshal(content, &records)
+    return records, err
+}
+
+// copyRow round-trips a row through JSON so stored rows hold the same types
+// a database would return (float64 numbers, []interface{} arrays)
+func copyRow(row map[string]interface{}) map[string]interface{} {
+    content, _ := json.Marshal(row)
+    var out map[string]interface{}
+    json.Unmarshal(content, &out)
+    return out
+}
+
+// Rows with an empty column returns every row of the table
+func (m *Memory) Rows(table, column, value string) ([]map[string]interface{}, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    rows := make([]map[string]interface{}, 0)
+    for _, row := range m.tables[table] {
+        if column == "" || fmt.Sprint(row[column]) == value {
+            rows = append(rows, copyRow(row))
+        }
+    }
+    sort.Slice(rows, func(i, j int) bool { return fmt.Sprint(rows[i]["id"]) < fmt.Sprint(rows[j]["id"]) })
+    return rows, nil
+}
+
+func (m *Memory) InsertRows(table string, rows []map[string]interface{}) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for _, row := range rows {
+        row = copyRow(row)
+        if row["id"] == nil { row["id"] = newUUID() }
+        m.tables[table] = append(m.tables[table], row)
+    }
+    return nil
+}
+
+func (m *Memory) UpdateRow(table, id string, values map[string]interface{}) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    for _, row := range m.tables[table] {
+        if fmt.Sprint(row["id"]) != id { continue }
+        for column, value := range copyRow(values) {
+            row[column] = value
+        }
+    }
+    return nil
+}
+
+// InTx runs fn on a copy of the league tables and keeps it only when fn
+// succeeds
+func (m *Memory) InTx(fn func(LeagueRepository) error) error {
+    m.mu.Lock()
+    saved := make(map[string][]map[string]interface{}, len(m.tables))
+    for table, rows := range m.tables {
+        copied := make([]map[string]interface{}, len(rows))
+        for i, row := range rows {
+            copied[i] = copyRow(row)
+        }
+        saved[table] = copied
+    }
+    m.mu.Unlock()
+
+    if err := fn(m); err != nil {
+        m.mu.Lock()
+        m.tables = saved
+        m.mu.Unlock()
+        return err
+    }
+    return nil
+}
+
+func (m *Memory) DeleteRows(table string, ids []string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    remove := make(map[string]bool, len(ids))
+    for _, id := range ids {
+        remove[id] = true
+    }
+    kept := m.tables[table][:0]
+    for _, row := range m.tables[table] {
+        if !remove[fmt.Sprint(row["id"])] { kept = append(kept, row) }
+    }
+    m.tables[table] = kept
+    return nil
+}
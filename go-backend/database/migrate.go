@@ -0,0 +1,144 @@
+package database
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "time"
+
+    "github.com/jackc/pgx/v5"
+)
+
+// Migration is a SQL file of the supabase directory. Version is its path
+// relative to that directory, which is also the key it is recorded under.
+type Migration struct {
+    Version  string
+    Path     string
+    SQL      string
+    Checksum string
+}
+
+// AppliedMigration is a row of schema_migrations
+type AppliedMigration struct {
+    Version   string
+    Checksum  string
+    AppliedAt time.Time
+}
+
+// Keeps concurrent runs from applying the same file twice
+const migrationLock = 7261934
+
+// MigrationFiles lists the migrations of a supabase directory in the order
+// they apply: schema.sql, then migrations/*.sql by name. With local the auth
+// stand-ins of local/auth.sql come first, for a plain PostgreSQL.
+func MigrationFiles(dir string, local bool) ([]Migration, error) {
+    var paths []string
+    if local { paths = append(paths, filepath.Join(dir, "local", "auth.sql")) }
+    paths = append(paths, filepath.Join(dir, "schema.sql"))
+
+    files, err := filepath.Glob(filepath.Join(dir, "migrations", "*.sql"))
+    if err != nil {
+        return nil, err
+    }
+    sort.Strings(files)
+    paths = append(paths, files...)
+
+    migrations := make([]Migration, 0, len(paths))
+    for _, path := range paths {
+        content, err := os.ReadFile(path)
+        if err != nil {
+            return nil, err
+        }
+        version, err := filepath.Rel(dir, path)
+        if err != nil {
+            return nil, err
+        }
+        sum := sha256.Sum256(content)
+        migrations = append(migrations, Migration{
+            Version:  filepath.ToSlash(version),
+            Path:     path,
+            SQL:      string(content),
+            Checksum: hex.EncodeToString(sum[:]),
+        })
+    }
+    return migrations, nil
+}
+
+func (p *Postgres) Close() {
+    p.pool.Close()
+}
+
+// AppliedMigrations returns the recorded migrations by version, creating the
+// tracking table on first use
+func (p *Postgres) AppliedMigrations() (map[string]AppliedMigration, error) {
+    _, err := p.pool.Exec(context.Background(), `
+        CREATE TABLE IF NOT EXISTS public.schema_migrations (
+            version TEXT PRIMARY KEY,
+            checksum TEXT NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        )`)
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := p.pool.Query(context.Background(), "SELECT version, checksum, applied_at FROM public.schema_migrations")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    applied := make(map[string]AppliedMigration)
+    for rows.Next() {
+        var m AppliedMigration
+        if err := rows.Scan(&m.Version, &m.Checksum, &m.AppliedAt); err != nil {
+            return nil, err
+        }
+        applied[m.Version] = m
+    }
+    return applied, rows.Err()
+}
+
+// ApplyMigration runs a migration and records it in one transaction, so a
+// failing file leaves nothing behind. It reports false when another run
+// applied the file first.
+func (p *Postgres) ApplyMigration(m Migration) (bool, error) {
+    return p.recordMigration(m, true)
+}
+
+// MarkMigration records a migration as applied without running it, for
+// databases that got the schema some other way
+func (p *Postgres) MarkMigration(m Migration) (bool, error) {
+    return p.recordMigration(m, false)
+}
+
+func (p *Postgres) recordMigration(m Migration, run bool) (bool, error) {
+    recorded := false
+    err := p.inTx(func(tx pgx.Tx) error {
+        ctx := context.Background()
+        if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLock); err != nil {
+            return err
+        }
+        var exists bool
+        if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM public.schema_migrations WHERE version = $1)", m.Version).Scan(&exists); err != nil {
+            return err
+        }
+        if exists { return nil }
+
+        if run {
+            // Without arguments pgx uses the simple protocol, which runs every statement of the file
+            if _, err := tx.Exec(ctx, m.SQL); err != nil {
+                return fmt.Errorf("%s: %w", m.Version, err)
+            }
+        }
+        if _, err := tx.Exec(ctx, "INSERT INTO public.schema_migrations (version, checksum) VALUES ($1, $2)", m.Version, m.Checksum); err != nil {
+            return err
+        }
+        recorded = true
+        return nil
+    })
+    return recorded, err
+}
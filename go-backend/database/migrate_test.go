@@ -0,0 +1,108 @@
+package database
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+const supabaseDir = "../../VolleySimulator-main/supabase"
+
+func TestMigrationFilesOrder(t *testing.T) {
+    migrations, err := MigrationFiles(supabaseDir, true)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(migrations) < 3 || migrations[0].Version != "local/auth.sql" || migrations[1].Version != "schema.sql" {
+        t.Fatalf("versions start %v, want local/auth.sql, schema.sql", versions(migrations))
+    }
+    for i, m := range migrations[2:] {
+        if !strings.HasPrefix(m.Version, "migrations/") {
+            t.Errorf("%s is not under migrations/", m.Version)
+        }
+        if i > 0 && m.Version < migrations[i+1].Version {
+            t.Errorf("%s is listed after %s", m.Version, migrations[i+1].Version)
+        }
+    }
+
+    hosted, _ := MigrationFiles(supabaseDir, false)
+    if len(hosted) != len(migrations)-1 || hosted[0].Version != "schema.sql" {
+        t.Errorf("without local: %v", versions(hosted))
+    }
+}
+
+func versions(migrations []Migration) []string {
+    out := make([]string, len(migrations))
+    for i, m := range migrations {
+        out[i] = m.Version
+    }
+    return out
+}
+
+// TestMigrateUp applies every migration to DATABASE_URL, which must be a
+// scratch PostgreSQL database: the test writes to it and leaves the schema
+// behind. Skipped when DATABASE_URL is not set.
+func TestMigrateUp(t *testing.T) {
+    url := os.Getenv("DATABASE_URL")
+    if url == "" {
+        t.Skip("DATABASE_URL not set")
+    }
+    pg, err := NewPostgres(url)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer pg.Close()
+
+    migrations, err := MigrationFiles(supabaseDir, true)
+    if err != nil {
+        t.Fatal(err)
+    }
+    applied, err := pg.AppliedMigrations()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, m := range migrations {
+        if _, ok := applied[m.Version]; ok { continue }
+        if _, err := pg.ApplyMigration(m); err != nil {
+            t.Fatalf("applying %s: %v", filepath.Base(m.Path), err)
+        }
+    }
+
+    // A second run finds everything recorded and applies nothing
+    applied, err = pg.AppliedMigrations()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, m := range migrations {
+        done, ok := applied[m.Version]
+        if !ok || done.Checksum != m.Checksum {
+            t.Errorf("%s: recorded %t, checksum %q, want %q", m.Version, ok, done.Checksum, m.Checksum)
+        }
+        if recorded, err := pg.ApplyMigration(m); err != nil || recorded {
+            t.Errorf("%s applied again: %t %v", m.Version, recorded, err)
+        }
+    }
+
+    // The league tables take the sync's writes, rolled back with their transaction
+    id := "migrate-test"
+    defer pg.db.Exec(context.Background(), "DELETE FROM leagues WHERE id = $1", id)
+    rollback := errors.New("rollback")
+    err = pg.InTx(func(repo LeagueRepository) error {
+        if err := repo.InsertRows("leagues", []map[string]interface{}{{"id": id, "name": "Test", "short_name": "T"}}); err != nil {
+            return err
+        }
+        if rows, err := repo.Rows("leagues", "id", id); err != nil || len(rows) != 1 {
+            t.Errorf("inside the transaction: %v %v", rows, err)
+        }
+        return rollback
+    })
+    if err != rollback {
+        t.Fatalf("InTx: %v", err)
+    }
+    if rows, err := pg.Rows("leagues", "id", id); err != nil || len(rows) != 0 {
+        t.Errorf("after the rollback: %v %v", rows, err)
+    }
+}
@@ -0,0 +1,434 @@
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgconn"
+    "github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres implements the repositories with SQL over a pgx pool, for
+// deployments that talk to the database directly instead of through
+// PostgREST. Rows are read as to_jsonb so they come out exactly as PostgREST
+// would return them.
+type Postgres struct {
+    pool *pgxpool.Pool
+    db   dbtx // the pool, or the transaction of a repository from InTx
+}
+
+// dbtx is what pgxpool.Pool and pgx.Tx have in common
+type dbtx interface {
+    Begin(ctx context.Context) (pgx.Tx, error)
+    Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+    Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+    QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+    pool, err := pgxpool.New(context.Background(), url)
+    if err != nil {
+        return nil, err
+    }
+    if err := pool.Ping(context.Background()); err != nil {
+        pool.Close()
+        return nil, err
+    }
+    return &Postgres{pool: pool, db: pool}, nil
+}
+
+func (p *Postgres) Repositories() *Repositories {
+    return &Repositories{Predictions: p, Results: p, Leaderboard: p, Profiles: p, Leagues: p, Locks: p}
+}
+
+// Advisory lock class of the scheduler's jobs, the key is hashed into the second half
+const jobLockClass = 7261935
+
+// TryLock takes a session advisory lock on a connection of its own, so
+// every instance of the API sharing the database sees it until release
+func (p *Postgres) TryLock(ctx context.Context, key string) (func(), bool, error) {
+    conn, err := p.pool.Acquire(ctx)
+    if err != nil {
+        return nil, false, err
+    }
+    var ok bool
+    err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", jobLockClass, key).Scan(&ok)
+    if err != nil || !ok {
+        conn.Release()
+        return nil, false, err
+    }
+
+    release := func() {
+        // Not the job's context, which may have run out
+        _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))", jobLockClass, key)
+        if err != nil {
+            // Ending the session drops its locks
+            conn.Conn().Close(context.Background())
+        }
+        conn.Release()
+    }
+    return release, true, nil
+}
+
+// querier is a pool or a transaction
+type querier interface {
+    Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// selectJSON runs a query returning one jsonb column and decodes the rows
+// into dest, a pointer to a slice
+func selectJSON(q querier, dest interface{}, sql string, args ...interface{}) error {
+    rows, err := q.Query(context.Background(), sql, args...)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    var buf strings.Builder
+    buf.WriteString("[")
+    for rows.Next() {
+        var row []byte
+        if err := rows.Scan(&row); err != nil {
+            return err
+        }
+        if buf.Len() > 1 { buf.WriteString(",") }
+        buf.Write(row)
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    buf.WriteString("]")
+    return json.Unmarshal([]byte(buf.String()), dest)
+}
+
+func (p *Postgres) count(table string) (int, error) {
+    var n int
+    err := p.db.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+pgx.Identifier{table}.Sanitize()).Scan(&n)
+    return n, err
+}
+
+// inTx runs fn in a transaction, committed when fn returns nil. Inside the
+// transaction of InTx it is a savepoint.
+func (p *Postgres) inTx(fn func(tx pgx.Tx) error) error {
+    return pgx.BeginFunc(context.Background(), p.db, fn)
+}
+
+func (p *Postgres) ListPredictions(userID string, filter PredictionFilter) ([]Prediction, error) {
+    sql := `SELECT to_jsonb(p) FROM predictions p
+        WHERE user_id = $1 AND ($2 = '' OR league = $2) AND ($3 = '' OR group_name = $3)
+        ORDER BY created_at DESC`
+    args := []interface{}{userID, filter.League, filter.Group}
+    if filter.Limit > 0 {
+        sql += " LIMIT $4"
+        args = append(args, filter.Limit)
+    }
+
+    predictions := make([]Prediction, 0)
+    err := selectJSON(p.db, &predictions, sql, args...)
+    return predictions, err
+}
+
+func (p *Postgres) SavePredictions(predictions []Prediction) error {
+    return p.inTx(func(tx pgx.Tx) error {
+        for _, pr := range predictions {
+            _, err := tx.Exec(context.Background(), `
+                INSERT INTO predictions (user_id, match_id, league, group_name, home_team, away_team, match_date, predicted_score)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+                ON CONFLICT (user_id, match_id) DO UPDATE SET
+                    league = EXCLUDED.league,
+                    group_name = EXCLUDED.group_name,
+                    home_team = EXCLUDED.home_team,
+                    away_team = EXCLUDED.away_team,
+                    match_date = EXCLUDED.match_date,
+                    predicted_score = EXCLUDED.predicted_score`,
+                pr.UserID, pr.MatchID, pr.League, pr.GroupName, pr.HomeTeam, pr.AwayTeam, pr.MatchDate, pr.PredictedScore)
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (p *Postgres) DeletePrediction(userID, matchID string) error {
+    _, err := p.db.Exec(context.Background(), "DELETE FROM predictions WHERE user_id = $1 AND match_id = $2", userID, matchID)
+    return err
+}
+
+func (p *Postgres) UnscoredPredictions(matchID string) ([]Prediction, error) {
+    var predictions []Prediction
+    err := selectJSON(p.db, &predictions, "SELECT to_jsonb(p) FROM predictions p WHERE match_id = $1 AND NOT is_scored", matchID)
+    return predictions, err
+}
+
+func (p *Postgres) MatchPredictions(matchID string) ([]Prediction, error) {
+    var predictions []Prediction
+    err := selectJSON(p.db, &predictions, "SELECT to_jsonb(p) FROM predictions p WHERE match_id = $1", matchID)
+    return predictions, err
+}
+
+func (p *Postgres) ScorePrediction(id string, points int) error {
+    _, err := p.db.Exec(context.Background(), "UPDATE predictions SET points_earned = $2, is_scored = TRUE WHERE id = $1", id, points)
+    return err
+}
+
+func (p *Postgres) CountPredictions() (int, error) {
+    return p.count("predictions")
+}
+
+func (p *Postgres) PendingMatchIDs() ([]string, error) {
+    rows, err := p.db.Query(context.Background(), "SELECT DISTINCT match_id FROM predictions WHERE NOT is_scored ORDER BY match_id")
+    if err != nil {
+        return nil, err
+    }
+    ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+    if ids == nil { ids = make([]string, 0) }
+    return ids, err
+}
+
+func (p *Postgres) ScoredPredictions() ([]Prediction, error) {
+    predictions := make([]Prediction, 0)
+    err := selectJSON(p.db, &predictions, "SELECT to_jsonb(p) FROM predictions p WHERE is_scored ORDER BY id")
+    return predictions, err
+}
+
+func (p *Postgres) SaveResult(r MatchResult) error {
+    homeSets, awaySets := r.sets()
+    _, err := p.db.Exec(context.Background(), `
+        INSERT INTO match_results (match_id, league, group_name, home_team, away_team, match_date, result_score, home_sets, away_sets, is_verified)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, to_jsonb($8::int[]), to_jsonb($9::int[]), $10)
+        ON CONFLICT (match_id) DO UPDATE SET
+            league = EXCLUDED.league,
+            group_name = EXCLUDED.group_name,
+            home_team = EXCLUDED.home_team,
+            away_team = EXCLUDED.away_team,
+            match_date = EXCLUDED.match_date,
+            result_score = EXCLUDED.result_score,
+            home_sets = EXCLUDED.home_sets,
+            away_sets = EXCLUDED.away_sets,
+            is_verified = EXCLUDED.is_verified`,
+        r.MatchID, r.League, r.GroupName, r.HomeTeam, r.AwayTeam, r.MatchDate, r.ResultScore, homeSets, awaySets, r.IsVerified)
+    return err
+}
+
+func (p *Postgres) SaveScoredResult(r MatchResult, points func(predicted string) int) (int, error) {
+    scored := 0
+    err := p.inTx(func(tx pgx.Tx) error {
+        repo := &Postgres{pool: p.pool, db: tx}
+        var previous string
+        err := tx.QueryRow(context.Background(), "SELECT result_score FROM match_results WHERE match_id = $1 FOR UPDATE", r.MatchID).Scan(&previous)
+        if err != nil && err != pgx.ErrNoRows {
+            return err
+        }
+        if err := repo.SaveResult(r); err != nil {
+            return err
+        }
+
+        predictions, err := repo.UnscoredPredictions(r.MatchID)
+        if previous != "" && previous != r.ResultScore {
+            predictions, err = repo.MatchPredictions(r.MatchID)
+        }
+        if err != nil {
+            return err
+        }
+        for _, pr := range predictions {
+            if err := repo.ScorePrediction(pr.ID, points(pr.PredictedScore)); err != nil {
+                return err
+            }
+        }
+        scored = len(predictions)
+        return nil
+    })
+    if err != nil {
+        return 0, err
+    }
+    return scored, nil
+}
+
+func (p *Postgres) CountResults() (int, error) {
+    return p.count("match_results")
+}
+
+func (p *Postgres) Leaderboard(period string, limit int) ([]LeaderboardEntry, error) {
+    sql := "SELECT to_jsonb(l) FROM leaderboard l ORDER BY " + pgx.Identifier{LeaderboardColumn(period)}.Sanitize() + " DESC NULLS LAST"
+    var args []interface{}
+    if limit > 0 {
+        sql += " LIMIT $1"
+        args = append(args, limit)
+    }
+
+    entries := make([]LeaderboardEntry, 0)
+    err := selectJSON(p.db, &entries, sql, args...)
+    return entries, err
+}
+
+func (p *Postgres) LeaderboardEntry(userID string) (*LeaderboardEntry, error) {
+    var entries []LeaderboardEntry
+    if err := selectJSON(p.db, &entries, "SELECT to_jsonb(l) FROM leaderboard l WHERE user_id = $1", userID); err != nil {
+        return nil, err
+    }
+    if len(entries) == 0 { return nil, nil }
+    return &entries[0], nil
+}
+
+func (p *Postgres) LeaderboardRank(period string, points int) (int, error) {
+    var above int
+    err := p.db.QueryRow(context.Background(),
+        "SELECT COUNT(*) FROM leaderboard WHERE "+pgx.Identifier{LeaderboardColumn(period)}.Sanitize()+" > $1",
+        points).Scan(&above)
+    return above + 1, err
+}
+
+func (p *Postgres) SetDisplayName(userID, name string) error {
+    _, err := p.db.Exec(context.Background(), "UPDATE leaderboard SET display_name = $2 WHERE user_id = $1", userID, name)
+    return err
+}
+
+func (p *Postgres) SaveLeaderboardStats(entries []LeaderboardEntry) error {
+    content, err := json.Marshal(entries)
+    if err != nil { return err }
+    _, err = p.db.Exec(context.Background(), `
+        INSERT INTO leaderboard (user_id, total_points, correct_predictions, partial_predictions, total_predictions,
+            current_streak, best_streak, weekly_points, monthly_points)
+        SELECT user_id, total_points, correct_predictions, partial_predictions, total_predictions,
+            current_streak, best_streak, weekly_points, monthly_points
+        FROM jsonb_populate_recordset(NULL::leaderboard, $1::jsonb)
+        ON CONFLICT (user_id) DO UPDATE SET
+            total_points = EXCLUDED.total_points,
+            correct_predictions = EXCLUDED.correct_predictions,
+            partial_predictions = EXCLUDED.partial_predictions,
+            total_predictions = EXCLUDED.total_predictions,
+            current_streak = EXCLUDED.current_streak,
+            best_streak = EXCLUDED.best_streak,
+            weekly_points = EXCLUDED.weekly_points,
+            monthly_points = EXCLUDED.monthly_points`,
+        string(content))
+    return err
+}
+
+func (p *Postgres) Profile(userID string) (*Profile, error) {
+    var profiles []Profile
+    if err := selectJSON(p.db, &profiles, "SELECT to_jsonb(p) FROM user_profiles p WHERE id = $1", userID); err != nil {
+        return nil, err
+    }
+    if len(profiles) == 0 { return nil, nil }
+    return &profiles[0], nil
+}
+
+func (p *Postgres) UpdateProfile(userID string, update ProfileUpdate) (*Profile, error) {
+    var profiles []Profile
+    err := selectJSON(p.db, &profiles, `
+        UPDATE user_profiles SET
+            display_name = COALESCE($2, display_name),
+            avatar_url = COALESCE($3, avatar_url),
+            favorite_team = COALESCE($4, favorite_team)
+        WHERE id = $1
+        RETURNING to_jsonb(user_profiles.*)`,
+        userID, update.DisplayName, update.AvatarURL, update.FavoriteTeam)
+    if err != nil {
+        return nil, err
+    }
+    if len(profiles) == 0 { return nil, nil }
+    return &profiles[0], nil
+}
+
+func (p *Postgres) CountProfiles() (int, error) {
+    return p.count("user_profiles")
+}
+
+func (p *Postgres) ListLeagues() ([]LeagueRecord, error) {
+    var rows []LeagueRecord
+    err := selectJSON(p.db, &rows, "SELECT to_jsonb(l) FROM leagues l ORDER BY id")
+    return rows, err
+}
+
+// InTx runs fn with repositories bound to one transaction, committed when fn
+// returns nil
+func (p *Postgres) InTx(fn func(LeagueRepository) error) error {
+    return p.inTx(func(tx pgx.Tx) error {
+        return fn(&Postgres{pool: p.pool, db: tx})
+    })
+}
+
+// The sync writes column maps, so table and column names come from callers;
+// only the league tables are accepted
+var leagueTables = map[string]bool{"leagues": true, "teams": true, "matches": true, "standings": true}
+
+func leagueTable(table string) (string, error) {
+    if !leagueTables[table] {
+        return "", fmt.Errorf("%s is not a league table", table)
+    }
+    return pgx.Identifier{table}.Sanitize(), nil
+}
+
+// columnList is the sorted, quoted column names of a row
+func columnList(values map[string]interface{}) string {
+    columns := make([]string, 0, len(values))
+    for column := range values {
+        columns = append(columns, pgx.Identifier{column}.Sanitize())
+    }
+    sort.Strings(columns)
+    return strings.Join(columns, ", ")
+}
+
+func (p *Postgres) Rows(table, column, value string) ([]map[string]interface{}, error) {
+    name, err := leagueTable(table)
+    if err != nil { return nil, err }
+
+    rows := make([]map[string]interface{}, 0)
+    err = selectJSON(p.db, &rows,
+        "SELECT to_jsonb(t) FROM "+name+" t WHERE "+pgx.Identifier{column}.Sanitize()+"::text = $1 ORDER BY id",
+        value)
+    return rows, err
+}
+
+// InsertRows inserts every row in one transaction. Values go in as JSON
+// through jsonb_populate_recordset, so Postgres converts them to the column
+// types and the columns left out keep their defaults.
+func (p *Postgres) InsertRows(table string, rows []map[string]interface{}) error {
+    name, err := leagueTable(table)
+    if err != nil { return err }
+
+    return p.inTx(func(tx pgx.Tx) error {
+        // Rows are grouped by column set, the sync sends the same columns for a table
+        groups := make(map[string][]map[string]interface{})
+        var order []string
+        for _, row := range rows {
+            columns := columnList(row)
+            if _, ok := groups[columns]; !ok { order = append(order, columns) }
+            groups[columns] = append(groups[columns], row)
+        }
+        for _, columns := range order {
+            content, err := json.Marshal(groups[columns])
+            if err != nil { return err }
+            sql := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM jsonb_populate_recordset(NULL::%s, $1::jsonb)", name, columns, columns, name)
+            if _, err := tx.Exec(context.Background(), sql, string(content)); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (p *Postgres) UpdateRow(table, id string, values map[string]interface{}) error {
+    name, err := leagueTable(table)
+    if err != nil { return err }
+
+    content, err := json.Marshal(values)
+    if err != nil { return err }
+    columns := columnList(values)
+    sql := fmt.Sprintf("UPDATE %s SET (%s) = (SELECT %s FROM jsonb_populate_record(NULL::%s, $2::jsonb)) WHERE id::text = $1", name, columns, columns, name)
+    _, err = p.db.Exec(context.Background(), sql, id, string(content))
+    return err
+}
+
+func (p *Postgres) DeleteRows(table string, ids []string) error {
+    name, err := leagueTable(table)
+    if err != nil { return err }
+    _, err = p.db.Exec(context.Background(), "DELETE FROM "+name+" WHERE id::text = ANY($1)", ids)
+    return err
+}
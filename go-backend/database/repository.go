@@ -0,0 +1,260 @@
+package database
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+)
+
+// Rows of the tables in supabase/schema.sql and the migrations. Nullable
+// columns are pointers; timestamps are kept as the database formats them.
+
+type Prediction struct {
+    ID             string  `json:"id,omitempty"`
+    UserID         string  `json:"user_id"`
+    MatchID        string  `json:"match_id"`
+    League         string  `json:"league"`
+    GroupName      string  `json:"group_name"`
+    HomeTeam       string  `json:"home_team"`
+    AwayTeam       string  `json:"away_team"`
+    MatchDate      *string `json:"match_date"`
+    PredictedScore string  `json:"predicted_score"`
+    PointsEarned   int     `json:"points_earned"`
+    IsScored       bool    `json:"is_scored"`
+    CreatedAt      string  `json:"created_at,omitempty"`
+    UpdatedAt      string  `json:"updated_at,omitempty"`
+}
+
+type PredictionFilter struct {
+    League string
+    Group  string
+    Limit  int // 0 for all
+}
+
+type MatchResult struct {
+    ID          string  `json:"id,omitempty"`
+    MatchID     string  `json:"match_id"`
+    League      string  `json:"league"`
+    GroupName   string  `json:"group_name"`
+    HomeTeam    string  `json:"home_team"`
+    AwayTeam    string  `json:"away_team"`
+    MatchDate   *string `json:"match_date"`
+    ResultScore string  `json:"result_score"`
+    HomeSets    []int   `json:"home_sets"` // rally points per set, empty when unknown
+    AwaySets    []int   `json:"away_sets"`
+    IsVerified  bool    `json:"is_verified"`
+    CreatedAt   string  `json:"created_at,omitempty"`
+}
+
+// sets returns the set columns, empty arrays rather than NULL when unknown
+func (r MatchResult) sets() ([]int, []int) {
+    home, away := r.HomeSets, r.AwaySets
+    if home == nil { home = make([]int, 0) }
+    if away == nil { away = make([]int, 0) }
+    return home, away
+}
+
+type LeaderboardEntry struct {
+    ID                 string  `json:"id,omitempty"`
+    UserID             string  `json:"user_id"`
+    DisplayName        *string `json:"display_name"`
+    TotalPoints        int     `json:"total_points"`
+    CorrectPredictions int     `json:"correct_predictions"`
+    PartialPredictions int     `json:"partial_predictions"`
+    TotalPredictions   int     `json:"total_predictions"`
+    CurrentStreak      int     `json:"current_streak"`
+    BestStreak         int     `json:"best_streak"`
+    WeeklyPoints       int     `json:"weekly_points"`
+    MonthlyPoints      int     `json:"monthly_points"`
+    UpdatedAt          string  `json:"updated_at,omitempty"`
+}
+
+// Leaderboard periods and the column each one is ranked by
+var leaderboardColumns = map[string]string{
+    "total":   "total_points",
+    "weekly":  "weekly_points",
+    "monthly": "monthly_points",
+}
+
+// LeaderboardColumn returns the points column of a period, total_points for unknown ones
+func LeaderboardColumn(period string) string {
+    if column, ok := leaderboardColumns[period]; ok { return column }
+    return "total_points"
+}
+
+// Points returns the entry's points for a period
+func (e LeaderboardEntry) Points(period string) int {
+    switch period {
+    case "weekly":
+        return e.WeeklyPoints
+    case "monthly":
+        return e.MonthlyPoints
+    }
+    return e.TotalPoints
+}
+
+// leaderboardStats is the columns SaveLeaderboardStats writes
+func leaderboardStats(e LeaderboardEntry) map[string]interface{} {
+    return map[string]interface{}{
+        "user_id":             e.UserID,
+        "total_points":        e.TotalPoints,
+        "correct_predictions": e.CorrectPredictions,
+        "partial_predictions": e.PartialPredictions,
+        "total_predictions":   e.TotalPredictions,
+        "current_streak":      e.CurrentStreak,
+        "best_streak":         e.BestStreak,
+        "weekly_points":       e.WeeklyPoints,
+        "monthly_points":      e.MonthlyPoints,
+    }
+}
+
+type Profile struct {
+    ID           string          `json:"id"`
+    DisplayName  *string         `json:"display_name"`
+    AvatarURL    *string         `json:"avatar_url"`
+    FavoriteTeam *string         `json:"favorite_team"`
+    Level        int             `json:"level"`
+    XP           int             `json:"xp"`
+    Achievements json.RawMessage `json:"achievements,omitempty"`
+    CreatedAt    string          `json:"created_at,omitempty"`
+    UpdatedAt    string          `json:"updated_at,omitempty"`
+}
+
+// ProfileUpdate holds the fields a user may change, nil fields are left alone
+type ProfileUpdate struct {
+    DisplayName  *string `json:"display_name,omitempty"`
+    AvatarURL    *string `json:"avatar_url,omitempty"`
+    FavoriteTeam *string `json:"favorite_team,omitempty"`
+}
+
+func (u ProfileUpdate) Empty() bool {
+    return u.DisplayName == nil && u.AvatarURL == nil && u.FavoriteTeam == nil
+}
+
+// LeagueRecord is a row of the leagues table
+type LeagueRecord struct {
+    ID                    string `json:"id"`
+    Name                  string `json:"name"`
+    ShortName             string `json:"short_name"`
+    Subtitle              string `json:"subtitle"`
+    Season                string `json:"season"`
+    Country               string `json:"country"`
+    Theme                 string `json:"theme"`
+    Icon                  string `json:"icon"`
+    HasGroups             bool   `json:"has_groups"`
+    HasRounds             bool   `json:"has_rounds"`
+    HasPlayoffs           bool   `json:"has_playoffs"`
+    PlayoffSpots          int    `json:"playoff_spots"`
+    SecondaryPlayoffSpots int    `json:"secondary_playoff_spots"`
+    RelegationSpots       int    `json:"relegation_spots"`
+    IsActive              bool   `json:"is_active"`
+}
+
+type PredictionRepository interface {
+    // Predictions of a user, newest first
+    ListPredictions(userID string, filter PredictionFilter) ([]Prediction, error)
+    // SavePredictions inserts or replaces predictions by user and match,
+    // points and scoring state are kept
+    SavePredictions(predictions []Prediction) error
+    DeletePrediction(userID, matchID string) error
+    // UnscoredPredictions lists the predictions of a match still waiting for points
+    UnscoredPredictions(matchID string) ([]Prediction, error)
+    // MatchPredictions lists every prediction of a match, for rescoring a corrected result
+    MatchPredictions(matchID string) ([]Prediction, error)
+    ScorePrediction(id string, points int) error
+    CountPredictions() (int, error)
+    // PendingMatchIDs lists the matches that have predictions waiting for points
+    PendingMatchIDs() ([]string, error)
+    // ScoredPredictions lists every scored prediction, for the leaderboard rollup
+    ScoredPredictions() ([]Prediction, error)
+}
+
+type MatchResultRepository interface {
+    // SaveResult inserts or replaces the result of a match by match id
+    SaveResult(result MatchResult) error
+    // SaveScoredResult saves a result and scores the predictions of its
+    // match as one unit: the unscored ones, or every one when the result
+    // replaces a different score. points gives a prediction's points from
+    // its predicted score. It returns the number of predictions scored.
+    SaveScoredResult(result MatchResult, points func(predicted string) int) (int, error)
+    CountResults() (int, error)
+}
+
+type LeaderboardRepository interface {
+    // Leaderboard lists the best entries of a period (total, weekly, monthly)
+    Leaderboard(period string, limit int) ([]LeaderboardEntry, error)
+    // LeaderboardEntry returns a user's entry, nil when the user has none
+    LeaderboardEntry(userID string) (*LeaderboardEntry, error)
+    // LeaderboardRank is one more than the number of entries with more points
+    LeaderboardRank(period string, points int) (int, error)
+    SetDisplayName(userID, name string) error
+    // SaveLeaderboardStats writes the point and prediction counters of the
+    // entries by user, creating missing entries. Display names are left alone.
+    SaveLeaderboardStats(entries []LeaderboardEntry) error
+}
+
+type ProfileRepository interface {
+    // Profile returns nil when the user has no profile row yet
+    Profile(userID string) (*Profile, error)
+    UpdateProfile(userID string, update ProfileUpdate) (*Profile, error)
+    CountProfiles() (int, error)
+}
+
+// LeagueRepository reads the league registry and gives the sync command
+// row access to the normalized league tables (leagues, teams, matches,
+// standings). Rows are column maps since the sync diffs them column by column.
+type LeagueRepository interface {
+    ListLeagues() ([]LeagueRecord, error)
+    // Rows returns the rows where column equals value, ordered by id
+    Rows(table, column, value string) ([]map[string]interface{}, error)
+    InsertRows(table string, rows []map[string]interface{}) error
+    UpdateRow(table, id string, values map[string]interface{}) error
+    DeleteRows(table string, ids []string) error
+    // InTx runs fn against a repository whose writes are committed together
+    // when fn returns nil and rolled back otherwise
+    InTx(fn func(LeagueRepository) error) error
+}
+
+// JobLocker coordinates the scheduler's jobs between instances of the API
+// sharing a database. TryLock reports false when another one holds the key.
+type JobLocker interface {
+    TryLock(ctx context.Context, key string) (release func(), ok bool, err error)
+}
+
+// Repositories is what handlers and commands get instead of a database client
+type Repositories struct {
+    Predictions PredictionRepository
+    Results     MatchResultRepository
+    Leaderboard LeaderboardRepository
+    Profiles    ProfileRepository
+    Leagues     LeagueRepository
+    Locks       JobLocker // nil when the backend cannot lock across instances
+}
+
+// Open returns the repositories of DATABASE_BACKEND: supabase (the default),
+// postgres to connect to DATABASE_URL directly, or memory, which needs no
+// database and starts empty
+func Open() (*Repositories, error) {
+    switch backend := os.Getenv("DATABASE_BACKEND"); backend {
+    case "", "supabase":
+        if err := Init(); err != nil {
+            return nil, err
+        }
+        return NewSupabase(Client).Repositories(), nil
+    case "postgres":
+        url := os.Getenv("DATABASE_URL")
+        if url == "" {
+            return nil, fmt.Errorf("DATABASE_URL must be set for the postgres backend")
+        }
+        pg, err := NewPostgres(url)
+        if err != nil {
+            return nil, err
+        }
+        return pg.Repositories(), nil
+    case "memory":
+        return NewMemory().Repositories(), nil
+    default:
+        return nil, fmt.Errorf("unknown DATABASE_BACKEND %q, use supabase, postgres or memory", backend)
+    }
+}
@@ -1,8 +1,12 @@
 package database
 
 import (
+    "encoding/json"
     "os"
     "fmt"
+    "strconv"
+
+    "github.com/supabase-community/postgrest-go"
     supa "github.com/supabase-community/supabase-go"
 )
 
@@ -20,3 +24,317 @@ func Init() error {
     Client, err = supa.NewClient(url, key, nil)
     return err
 }
+
+const (
+    postgrestPage  = 1000 // PostgREST's default row limit
+    postgrestBatch = 200  // rows per insert, ids per delete filter
+)
+
+// Supabase implements the repositories over PostgREST with the service role key
+type Supabase struct {
+    client *supa.Client
+}
+
+func NewSupabase(client *supa.Client) *Supabase {
+    return &Supabase{client: client}
+}
+
+func (s *Supabase) Repositories() *Repositories {
+    return &Repositories{Predictions: s, Results: s, Leaderboard: s, Profiles: s, Leagues: s}
+}
+
+func (s *Supabase) count(table string) (int, error) {
+    _, n, err := s.client.From(table).Select("id", "exact", true).Execute()
+    return int(n), err
+}
+
+func (s *Supabase) ListPredictions(userID string, filter PredictionFilter) ([]Prediction, error) {
+    query := s.client.From("predictions").
+        Select("*", "", false).
+        Eq("user_id", userID).
+        Order("created_at", &postgrest.OrderOpts{Ascending: false})
+    if filter.League != "" { query = query.Eq("league", filter.League) }
+    if filter.Group != "" { query = query.Eq("group_name", filter.Group) }
+    if filter.Limit > 0 { query = query.Limit(filter.Limit, "") }
+
+    predictions := make([]Prediction, 0)
+    _, err := query.ExecuteTo(&predictions)
+    return predictions, err
+}
+
+func (s *Supabase) SavePredictions(predictions []Prediction) error {
+    records := make([]map[string]interface{}, 0, len(predictions))
+    for _, p := range predictions {
+        records = append(records, map[string]interface{}{
+            "user_id":         p.UserID,
+            "match_id":        p.MatchID,
+            "league":          p.League,
+            "group_name":      p.GroupName,
+            "home_team":       p.HomeTeam,
+            "away_team":       p.AwayTeam,
+            "match_date":      p.MatchDate,
+            "predicted_score": p.PredictedScore,
+        })
+    }
+    _, _, err := s.client.From("predictions").Upsert(records, "user_id,match_id", "minimal", "").Execute()
+    return err
+}
+
+func (s *Supabase) DeletePrediction(userID, matchID string) error {
+    _, _, err := s.client.From("predictions").
+        Delete("minimal", "").
+        Eq("user_id", userID).
+        Eq("match_id", matchID).
+        Execute()
+    return err
+}
+
+func (s *Supabase) UnscoredPredictions(matchID string) ([]Prediction, error) {
+    var predictions []Prediction
+    _, err := s.client.From("predictions").
+        Select("*", "", false).
+        Eq("match_id", matchID).
+        Eq("is_scored", "false").
+        ExecuteTo(&predictions)
+    return predictions, err
+}
+
+func (s *Supabase) MatchPredictions(matchID string) ([]Prediction, error) {
+    var predictions []Prediction
+    _, err := s.client.From("predictions").
+        Select("*", "", false).
+        Eq("match_id", matchID).
+        ExecuteTo(&predictions)
+    return predictions, err
+}
+
+func (s *Supabase) ScorePrediction(id string, points int) error {
+    _, _, err := s.client.From("predictions").Update(map[string]interface{}{
+        "points_earned": points,
+        "is_scored":     true,
+    }, "minimal", "").Eq("id", id).Execute()
+    return err
+}
+
+func (s *Supabase) CountPredictions() (int, error) {
+    return s.count("predictions")
+}
+
+// selectAll reads every row of a filtered query page by page
+func (s *Supabase) selectAll(table, columns, column, value string, dest func(page []byte) (int, error)) error {
+    for from := 0; ; from += postgrestPage {
+        content, _, err := s.client.From(table).Select(columns, "", false).
+            Eq(column, value).
+            Order("id", &postgrest.OrderOpts{Ascending: true}).
+            Range(from, from+postgrestPage-1, "").
+            Execute()
+        if err != nil {
+            return err
+        }
+        n, err := dest(content)
+        if err != nil || n < postgrestPage { return err }
+    }
+}
+
+func (s *Supabase) PendingMatchIDs() ([]string, error) {
+    seen := make(map[string]bool)
+    ids := make([]string, 0)
+    err := s.selectAll("predictions", "id,match_id", "is_scored", "false", func(page []byte) (int, error) {
+        var rows []Prediction
+        if err := json.Unmarshal(page, &rows); err != nil { return 0, err }
+        for _, r := range rows {
+            if !seen[r.MatchID] { seen[r.MatchID] = true; ids = append(ids, r.MatchID) }
+        }
+        return len(rows), nil
+    })
+    return ids, err
+}
+
+func (s *Supabase) ScoredPredictions() ([]Prediction, error) {
+    predictions := make([]Prediction, 0)
+    err := s.selectAll("predictions", "*", "is_scored", "true", func(page []byte) (int, error) {
+        var rows []Prediction
+        if err := json.Unmarshal(page, &rows); err != nil { return 0, err }
+        predictions = append(predictions, rows...)
+        return len(rows), nil
+    })
+    return predictions, err
+}
+
+func (s *Supabase) SaveResult(r MatchResult) error {
+    homeSets, awaySets := r.sets()
+    _, _, err := s.client.From("match_results").Upsert(map[string]interface{}{
+        "match_id":     r.MatchID,
+        "league":       r.League,
+        "group_name":   r.GroupName,
+        "home_team":    r.HomeTeam,
+        "away_team":    r.AwayTeam,
+        "match_date":   r.MatchDate,
+        "result_score": r.ResultScore,
+        "home_sets":    homeSets,
+        "away_sets":    awaySets,
+        "is_verified":  r.IsVerified,
+    }, "match_id", "minimal", "").Execute()
+    return err
+}
+
+// SaveScoredResult makes one PostgREST request per statement, so unlike the
+// postgres backend a failure can leave the result saved with some of its
+// predictions unscored; running it again finishes the scoring.
+func (s *Supabase) SaveScoredResult(r MatchResult, points func(predicted string) int) (int, error) {
+    var previous []MatchResult
+    _, err := s.client.From("match_results").Select("result_score", "", false).Eq("match_id", r.MatchID).ExecuteTo(&previous)
+    if err != nil {
+        return 0, err
+    }
+    if err := s.SaveResult(r); err != nil {
+        return 0, err
+    }
+
+    predictions, err := s.UnscoredPredictions(r.MatchID)
+    if len(previous) > 0 && previous[0].ResultScore != r.ResultScore {
+        predictions, err = s.MatchPredictions(r.MatchID)
+    }
+    if err != nil {
+        return 0, err
+    }
+    for i, p := range predictions {
+        if err := s.ScorePrediction(p.ID, points(p.PredictedScore)); err != nil {
+            return i, err
+        }
+    }
+    return len(predictions), nil
+}
+
+func (s *Supabase) CountResults() (int, error) {
+    return s.count("match_results")
+}
+
+func (s *Supabase) Leaderboard(period string, limit int) ([]LeaderboardEntry, error) {
+    query := s.client.From("leaderboard").
+        Select("*", "", false).
+        Order(LeaderboardColumn(period), &postgrest.OrderOpts{Ascending: false})
+    if limit > 0 { query = query.Limit(limit, "") }
+
+    entries := make([]LeaderboardEntry, 0)
+    _, err := query.ExecuteTo(&entries)
+    return entries, err
+}
+
+func (s *Supabase) LeaderboardEntry(userID string) (*LeaderboardEntry, error) {
+    var entries []LeaderboardEntry
+    if _, err := s.client.From("leaderboard").Select("*", "", false).Eq("user_id", userID).ExecuteTo(&entries); err != nil {
+        return nil, err
+    }
+    if len(entries) == 0 { return nil, nil }
+    return &entries[0], nil
+}
+
+func (s *Supabase) LeaderboardRank(period string, points int) (int, error) {
+    _, above, err := s.client.From("leaderboard").
+        Select("user_id", "exact", true).
+        Gt(LeaderboardColumn(period), strconv.Itoa(points)).
+        Execute()
+    return int(above) + 1, err
+}
+
+func (s *Supabase) SetDisplayName(userID, name string) error {
+    _, _, err := s.client.From("leaderboard").
+        Update(map[string]interface{}{"display_name": name}, "minimal", "").
+        Eq("user_id", userID).
+        Execute()
+    return err
+}
+
+func (s *Supabase) SaveLeaderboardStats(entries []LeaderboardEntry) error {
+    records := make([]map[string]interface{}, 0, len(entries))
+    for _, e := range entries {
+        records = append(records, leaderboardStats(e))
+    }
+    for start := 0; start < len(records); start += postgrestBatch {
+        end := start + postgrestBatch
+        if end > len(records) { end = len(records) }
+        if _, _, err := s.client.From("leaderboard").Upsert(records[start:end], "user_id", "minimal", "").Execute(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *Supabase) Profile(userID string) (*Profile, error) {
+    var profiles []Profile
+    if _, err := s.client.From("user_profiles").Select("*", "", false).Eq("id", userID).ExecuteTo(&profiles); err != nil {
+        return nil, err
+    }
+    if len(profiles) == 0 { return nil, nil }
+    return &profiles[0], nil
+}
+
+func (s *Supabase) UpdateProfile(userID string, update ProfileUpdate) (*Profile, error) {
+    var profiles []Profile
+    if _, err := s.client.From("user_profiles").Update(update, "representation", "").Eq("id", userID).ExecuteTo(&profiles); err != nil {
+        return nil, err
+    }
+    if len(profiles) == 0 { return nil, nil }
+    return &profiles[0], nil
+}
+
+func (s *Supabase) CountProfiles() (int, error) {
+    return s.count("user_profiles")
+}
+
+func (s *Supabase) ListLeagues() ([]LeagueRecord, error) {
+    var rows []LeagueRecord
+    _, err := s.client.From("leagues").Select("*", "", false).ExecuteTo(&rows)
+    return rows, err
+}
+
+func (s *Supabase) Rows(table, column, value string) ([]map[string]interface{}, error) {
+    var all []map[string]interface{}
+    for from := 0; ; from += postgrestPage {
+        var rows []map[string]interface{}
+        _, err := s.client.From(table).Select("*", "", false).
+            Eq(column, value).
+            Order("id", &postgrest.OrderOpts{Ascending: true}).
+            Range(from, from+postgrestPage-1, "").
+            ExecuteTo(&rows)
+        if err != nil {
+            return nil, err
+        }
+        all = append(all, rows...)
+        if len(rows) < postgrestPage { return all, nil }
+    }
+}
+
+func (s *Supabase) InsertRows(table string, rows []map[string]interface{}) error {
+    for start := 0; start < len(rows); start += postgrestBatch {
+        end := start + postgrestBatch
+        if end > len(rows) { end = len(rows) }
+        if _, _, err := s.client.From(table).Insert(rows[start:end], false, "", "minimal", "").Execute(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *Supabase) UpdateRow(table, id string, values map[string]interface{}) error {
+    _, _, err := s.client.From(table).Update(values, "minimal", "").Eq("id", id).Execute()
+    return err
+}
+
+// InTx runs fn directly: PostgREST has no transactions across requests, so
+// a failed sync can leave a league half written until the next run
+func (s *Supabase) InTx(fn func(LeagueRepository) error) error {
+    return fn(s)
+}
+
+func (s *Supabase) DeleteRows(table string, ids []string) error {
+    for start := 0; start < len(ids); start += postgrestBatch {
+        end := start + postgrestBatch
+        if end > len(ids) { end = len(ids) }
+        if _, _, err := s.client.From(table).Delete("minimal", "").In("id", ids[start:end]).Execute(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
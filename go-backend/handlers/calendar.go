@@ -0,0 +1,155 @@
+package handlers
+
+import (
+    "bytes"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+const matchDuration = 2 * time.Hour
+
+// matchUIDs gives every fixture of a league an event UID from the league,
+// the teams and the leg, numbering repeated pairings (playoff series) in
+// file order. It stays the same when a match is moved or the fixture is
+// renumbered, so calendar apps update the event instead of adding a new one.
+func matchUIDs(leagueID string, l *league.League) []string {
+    uids := make([]string, len(l.Fixture))
+    seen := make(map[string]int)
+    for i, f := range l.Fixture {
+        key := leagueID + "-" + teamKey(f.HomeTeamID, f.HomeTeam) + "-" + teamKey(f.AwayTeamID, f.AwayTeam)
+        if f.Leg != 0 { key += fmt.Sprintf("-leg%d", f.Leg) }
+        if f.GoldenSet { key += "-golden" }
+        seen[key]++
+        if n := seen[key]; n > 1 { key += fmt.Sprintf("-%d", n) }
+        uids[i] = utils.Slugify(key) + "@volleysimulator.com.tr"
+    }
+    return uids
+}
+
+func teamKey(id, name string) string {
+    if id != "" { return id }
+    return utils.TeamKey(name)
+}
+
+// matchEvent turns a fixture into a calendar event, false for matches
+// without a date. Matches of the current files carry the number and time
+// of the changes recorded under their fixture key.
+func matchEvent(cfg league.Config, l *league.League, f league.Fixture, uid, key string) (utils.CalendarEvent, bool) {
+    day, err := time.ParseInLocation("2006-01-02", f.Date, utils.TurkeyTime)
+    if err != nil {
+        return utils.CalendarEvent{}, false
+    }
+
+    e := utils.CalendarEvent{
+        UID:          uid,
+        Start:        day,
+        AllDay:       true,
+        Duration:     matchDuration,
+        Summary:      f.HomeTeam + " - " + f.AwayTeam,
+        LastModified: l.Modified(),
+    }
+    if start, err := time.ParseInLocation("2006-01-02 15:04", f.Date+" "+f.MatchTime, utils.TurkeyTime); err == nil {
+        e.Start, e.AllDay = start, false
+    }
+    if current, ok := league.Data.League(cfg.ID); ok && current == l && league.Data.Changes() != nil {
+        var changed time.Time
+        if e.Sequence, changed = league.Data.Changes().Revision(cfg.ID, key); !changed.IsZero() {
+            e.LastModified = changed
+        }
+    }
+
+    details := []string{cfg.Name}
+    switch {
+    case f.Round != "":
+        details = append(details, f.Round)
+    case f.Week != 0:
+        details = append(details, fmt.Sprintf("Hafta %d", f.Week))
+    }
+    if f.GroupName != "" && f.GroupName != cfg.Name && f.GroupName != l.Name {
+        details = append(details, f.GroupName)
+    }
+    if f.Leg != 0 { details = append(details, fmt.Sprintf("%d. Maç", f.Leg)) }
+
+    if f.IsPlayed && f.Score() != "" {
+        e.Summary = fmt.Sprintf("%s %s %s", f.HomeTeam, f.Score(), f.AwayTeam)
+        if f.SetResults != "" { details = append(details, f.SetResults) }
+    }
+    e.Description = strings.Join(details, "\n")
+
+    var place []string
+    for _, p := range []string{f.Venue, f.City} {
+        if p != "" { place = append(place, p) }
+    }
+    e.Location = strings.Join(place, ", ")
+    return e, true
+}
+
+func sendCalendar(c *fiber.Ctx, name string, events []utils.CalendarEvent) error {
+    var buf bytes.Buffer
+    utils.WriteCalendar(&buf, name, utils.TurkeyTime, events)
+    c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+    return c.Send(buf.Bytes())
+}
+
+// GetLeagueCalendar serves a league's fixture as an .ics feed, ?group= for one group
+func GetLeagueCalendar(c *fiber.Ctx) error {
+    leagueID := c.Params("id")
+    cfg, ok := league.Leagues.Get(leagueID)
+    l, loaded := findLeague(c, leagueID)
+    if !ok || !loaded {
+        return c.Status(404).JSON(fiber.Map{"error": "League not found"})
+    }
+
+    group := c.Query("group")
+    name := cfg.Name
+    if group != "" { name += " - " + group }
+
+    events := make([]utils.CalendarEvent, 0)
+    uids, keys := matchUIDs(cfg.ID, l), league.FixtureKeys(l.Fixture)
+    for i, f := range l.Fixture {
+        if group != "" && !strings.EqualFold(f.GroupName, group) { continue }
+        if e, ok := matchEvent(cfg, l, f, uids[i], keys[i]); ok {
+            events = append(events, e)
+        }
+    }
+    return sendCalendar(c, name, events)
+}
+
+// GetTeamCalendar serves every match of a team across competitions as an .ics feed
+func GetTeamCalendar(c *fiber.Ctx) error {
+    leagues := seasonLeagues(c)
+    configs := league.Leagues.All()
+
+    slug := c.Params("slug")
+    if unescaped, err := url.PathUnescape(slug); err == nil {
+        slug = unescaped
+    }
+
+    teamID, candidates := resolveTeamID([]map[string]*league.League{leagues}, configs, slug)
+    if teamID == "" {
+        return teamNotFound(c, candidates)
+    }
+
+    events := make([]utils.CalendarEvent, 0)
+    for _, cfg := range configs {
+        l, ok := leagues[cfg.ID]
+        // tvf mirrors 2lig, skip copies of a league that is loaded itself
+        if !ok || mirrors(leagues, cfg) { continue }
+
+        uids, keys := matchUIDs(cfg.ID, l), league.FixtureKeys(l.Fixture)
+        for i, f := range l.Fixture {
+            if f.HomeTeamID != teamID && f.AwayTeamID != teamID { continue }
+            if e, ok := matchEvent(cfg, l, f, uids[i], keys[i]); ok {
+                events = append(events, e)
+            }
+        }
+    }
+    name, _ := teamRef([]map[string]*league.League{leagues}, teamID)["name"].(string)
+    return sendCalendar(c, name, events)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+    "errors"
+    "strconv"
+    "time"
+    "github.com/gofiber/fiber/v2"
+
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+const (
+    changesPageSize = 500
+    changesMaxPage  = 2000
+)
+
+// GetChanges lists the data changelog. ?since= is a change id, the cursor of
+// the previous response, or a time (RFC 3339 or YYYY-MM-DD); ?league= and
+// ?kind= narrow it down.
+func GetChanges(c *fiber.Ctx) error {
+    changes := league.Data.Changes()
+    if changes == nil {
+        return c.Status(503).JSON(fiber.Map{"error": "Changelog is not available"})
+    }
+
+    filter := league.ChangeFilter{League: c.Query("league"), Kind: c.Query("kind"), Limit: c.QueryInt("limit", changesPageSize)}
+    if filter.Limit <= 0 || filter.Limit > changesMaxPage { filter.Limit = changesMaxPage }
+
+    if since := c.Query("since"); since != "" {
+        if id, err := strconv.Atoi(since); err == nil {
+            filter.AfterID = id
+        } else if t, err := time.Parse(time.RFC3339, since); err == nil {
+            filter.Since = t
+        } else if t, err := time.ParseInLocation("2006-01-02", since, utils.TurkeyTime); err == nil {
+            filter.Since = t
+        } else {
+            return c.Status(400).JSON(fiber.Map{"error": "since must be a change id, an RFC 3339 time or YYYY-MM-DD"})
+        }
+    }
+
+    // +1 to tell whether there is another page
+    limit := filter.Limit
+    filter.Limit++
+    list, err := changes.Changes(filter)
+    if errors.Is(err, league.ErrChangesGone) {
+        return c.Status(410).JSON(fiber.Map{"error": "Changes this old are no longer kept, start again without since"})
+    }
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to read the changelog: " + err.Error()})
+    }
+    hasMore := len(list) > limit
+    if hasMore { list = list[:limit] }
+
+    cursor := filter.AfterID
+    if len(list) > 0 {
+        cursor = list[len(list)-1].ID
+    } else if filter.AfterID == 0 {
+        cursor = changes.LastID()
+    }
+
+    return c.JSON(fiber.Map{
+        "changes": list,
+        "cursor":  cursor,
+        "hasMore": hasMore,
+    })
+}
@@ -1,20 +1,58 @@
 package handlers
 
 import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
     "os"
     "strings"
+    "time"
     "github.com/gofiber/fiber/v2"
     "go-backend/database"
+    "go-backend/league"
+    "go-backend/scheduler"
+    "go-backend/scraper"
+    "go-backend/utils"
 )
 
 type ResultInput struct {
-    MatchID     string `json:"matchId"`
-    League      string `json:"league"`
-    GroupName   string `json:"groupName"`
-    HomeTeam    string `json:"homeTeam"`
-    AwayTeam    string `json:"awayTeam"`
-    MatchDate   string `json:"matchDate"`
-    ResultScore string `json:"resultScore"`
+    MatchID     string           `json:"matchId"`
+    League      string           `json:"league"`
+    GroupName   string           `json:"groupName"`
+    HomeTeam    string           `json:"homeTeam"`
+    AwayTeam    string           `json:"awayTeam"`
+    MatchDate   string           `json:"matchDate"`
+    ResultScore string           `json:"resultScore"`
+    Sets        []utils.SetScore `json:"sets,omitempty"` // rally points per set, [{"home": 25, "away": 20}, ...]
+}
+
+// checkResult validates a result before it is saved: a finished best-of-five
+// score and, when sets are given, set scores that produce it
+func checkResult(res ResultInput) error {
+    home, away, ok := utils.ParseScore(res.ResultScore)
+    if !ok {
+        return fmt.Errorf("resultScore %q is not a score like 3-1", res.ResultScore)
+    }
+    if len(res.Sets) == 0 {
+        return utils.CheckResult(home, away)
+    }
+    return utils.CheckSets(res.Sets, home, away)
+}
+
+// scrapedSets are the set scores of a scraped or file result, left out when
+// they do not add up so a broken set column does not hold back the result
+func scrapedSets(setResults, score string) []utils.SetScore {
+    sets := utils.ParseSets(setResults)
+    home, away, ok := utils.ParseScore(score)
+    if len(sets) == 0 || !ok { return nil }
+    // A match in progress has no final sets to check yet
+    if utils.CheckResult(home, away) != nil { return nil }
+    if err := utils.CheckSets(sets, home, away); err != nil {
+        log.Printf("Dropping set scores %q of a %s result: %v", setResults, score, err)
+        return nil
+    }
+    return sets
 }
 
 type SyncResultsRequest struct {
@@ -40,11 +78,14 @@ func calculatePoints(predicted, actual string) int {
     return 0
 }
 
-func SyncResults(c *fiber.Ctx) error {
-    authHeader := c.Get("Authorization")
+// cronAuthorized checks the CRON_SECRET bearer token, open when no secret is set
+func cronAuthorized(c *fiber.Ctx) bool {
     secret := os.Getenv("CRON_SECRET")
-    
-    if secret != "" && authHeader != "Bearer "+secret {
+    return secret == "" || c.Get("Authorization") == "Bearer "+secret
+}
+
+func SyncResults(c *fiber.Ctx) error {
+    if !cronAuthorized(c) {
          return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
     }
 
@@ -53,67 +94,176 @@ func SyncResults(c *fiber.Ctx) error {
          return c.Status(400).JSON(fiber.Map{"error": "Invalid JSON"})
     }
 
-    savedResults := 0
-    scoredPredictions := 0
-    
-    for _, res := range req.Results {
-        // Upsert result
-        _, _, err := database.Client.From("match_results").Upsert(map[string]interface{}{
-            "match_id": res.MatchID,
-            "league": res.League,
-            "group_name": res.GroupName,
-            "home_team": res.HomeTeam,
-            "away_team": res.AwayTeam,
-            "match_date": res.MatchDate,
-            "result_score": res.ResultScore,
-            "is_verified": true,
-        }, "", "", "").Execute()
-        
-        if err == nil { 
-            savedResults++
-            if count, err := processMatchResult(res.MatchID, res.ResultScore); err == nil {
-                scoredPredictions += count
-            }
-        }
-    }
+    savedResults, scoredPredictions, rejected := saveResults(context.Background(), req.Results, "sync")
 
     return c.JSON(fiber.Map{
         "success": true,
         "savedResults": savedResults,
         "scoredPredictions": scoredPredictions,
+        "rejected": rejected,
     })
 }
 
-// processMatchResult calculates points for all pending predictions of a match
-func processMatchResult(matchID, resultScore string) (int, error) {
-    var preds []map[string]interface{}
-    _, err := database.Client.From("predictions").
-        Select("*", "", false).
-        Eq("match_id", matchID).
-        Eq("is_scored", "false").
-        ExecuteTo(&preds)
-    
+// saveResults stores verified results and scores the predictions waiting for
+// them. source names the ingestion path in the changelog. Results that fail
+// checkResult are skipped and listed in rejected. It stops early once ctx
+// is done, callers check ctx.Err().
+func saveResults(ctx context.Context, results []ResultInput, source string) (savedResults, scoredPredictions int, rejected []string) {
+    rejected = make([]string, 0)
+    for _, res := range results {
+        if ctx.Err() != nil { break }
+        if err := checkResult(res); err != nil {
+            rejected = append(rejected, res.MatchID+": "+err.Error())
+            continue
+        }
+
+        count, err := scoreResult(source, res)
+        if err != nil {
+            log.Printf("Saving result %s (%s): %v", res.MatchID, res.League, err)
+            continue
+        }
+        savedResults++
+        scoredPredictions += count
+        if count > 0 { queueNotification(res, count) }
+    }
+    return savedResults, scoredPredictions, rejected
+}
+
+// scoreResult saves a checked result together with the points of its
+// predictions, then records it in the changelog
+func scoreResult(source string, res ResultInput) (int, error) {
+    homeSets, awaySets := utils.SplitSets(res.Sets)
+    count, err := saveScoredResult(database.MatchResult{
+        MatchID:     res.MatchID,
+        League:      res.League,
+        GroupName:   res.GroupName,
+        HomeTeam:    res.HomeTeam,
+        AwayTeam:    res.AwayTeam,
+        MatchDate:   optionalDate(res.MatchDate),
+        ResultScore: res.ResultScore,
+        HomeSets:    homeSets,
+        AwaySets:    awaySets,
+        IsVerified:  true,
+    })
     if err != nil {
         return 0, err
     }
-        
-    scoredCount := 0
-    for _, p := range preds {
-        predScore := p["predicted_score"].(string)
-        points := calculatePoints(predScore, resultScore)
-        
-        database.Client.From("predictions").Update(map[string]interface{}{
-            "points_earned": points,
-            "is_scored": true,
-        }, "", "").Eq("id", p["id"].(string)).Execute()
-        scoredCount++
-    }
-    return scoredCount, nil
+
+    league.Data.RecordResult(source, res.League, res.MatchID, res.HomeTeam, res.AwayTeam, league.FixtureState{
+        Date:       res.MatchDate,
+        Score:      res.ResultScore,
+        SetResults: utils.FormatSets(res.Sets),
+    })
+    return count, nil
 }
 
+// saveScoredResult upserts a result and scores its match's predictions in
+// one transaction, every prediction again when the score changed
+func saveScoredResult(result database.MatchResult) (int, error) {
+    return repos.Results.SaveScoredResult(result, func(predicted string) int {
+        return calculatePoints(predicted, result.ResultScore)
+    })
+}
 
+// optionalDate leaves a missing match date NULL instead of an invalid ""
+func optionalDate(date string) *string {
+    if date == "" { return nil }
+    return &date
+}
+
+// UpdateResults scrapes the TVF calendar page (TVF_TAKVIM_URL overrides the
+// address) and feeds the played matches of our leagues into the results sync.
+// It runs as the scrape-tvf job, so it answers 409 while the job is running
+// and is cut off at the job's timeout.
 func UpdateResults(c *fiber.Ctx) error {
-    return c.Status(501).JSON(fiber.Map{
-        "message": "Scraping functionality is external (Node.js).",
+    if !cronAuthorized(c) {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    start := time.Now()
+    var stats scrapeStats
+    var scrapeErr error
+    scrape := func(ctx context.Context) (string, error) {
+        stats, scrapeErr = scrapeTVFResults(ctx)
+        return stats.summary(), scrapeErr
+    }
+
+    var run scheduler.Run
+    var err error
+    if scheduler.Jobs != nil {
+        run, err = scheduler.Jobs.Exclusive("scrape-tvf", "cron", scrape)
+    }
+    switch {
+    case scheduler.Jobs == nil || errors.Is(err, scheduler.ErrUnknownJob):
+        // No job config to share the single-run slot with
+        scrape(context.Background())
+    case errors.Is(err, scheduler.ErrRunning) || errors.Is(err, scheduler.ErrLocked):
+        return c.Status(409).JSON(fiber.Map{"success": false, "error": err.Error()})
+    case run.Outcome == scheduler.Failed && scrapeErr == nil:
+        scrapeErr = errors.New(run.Error) // a recovered panic
+    }
+    if scrapeErr != nil {
+        return c.Status(502).JSON(fiber.Map{"success": false, "error": scrapeErr.Error()})
+    }
+
+    return c.JSON(fiber.Map{
+        "success": true,
+        "duration": time.Since(start).String(),
+        "fetchedCount": stats.Fetched,
+        "matchedCount": stats.Matched,
+        "inProgress": stats.InProgress,
+        "savedResults": stats.Saved,
+        "scoredPredictions": stats.Scored,
+        "unmatched": stats.Unmatched,
+        "rejected": stats.Rejected,
     })
 }
+
+type scrapeStats struct {
+    Fetched, Matched, InProgress, Saved, Scored int
+    Unmatched, Rejected []string
+}
+
+func (s scrapeStats) summary() string {
+    return fmt.Sprintf("%d rows, %d matched, %d in progress, %d saved, %d predictions scored, %d unmatched, %d rejected",
+        s.Fetched, s.Matched, s.InProgress, s.Saved, s.Scored, len(s.Unmatched), len(s.Rejected))
+}
+
+// scrapeTVFResults is the work of UpdateResults and the scrape-tvf job
+func scrapeTVFResults(ctx context.Context) (scrapeStats, error) {
+    url := os.Getenv("TVF_TAKVIM_URL")
+    if url == "" { url = scraper.TVFTakvimURL }
+
+    results, err := scraper.FetchTVF(ctx, url)
+    if err != nil {
+        return scrapeStats{}, err
+    }
+
+    matched, unmatched := scraper.Resolve(results, league.Data.Snapshot().Leagues)
+    stats := scrapeStats{Fetched: len(results), Matched: len(matched)}
+    inputs := make([]ResultInput, 0, len(matched))
+    for _, m := range matched {
+        // The page shows live scores; the result comes on a later run
+        if !m.Finished() {
+            stats.InProgress++
+            continue
+        }
+        inputs = append(inputs, ResultInput{
+            MatchID:     m.MatchID(),
+            League:      m.League,
+            GroupName:   m.Fixture.GroupName,
+            HomeTeam:    m.Fixture.HomeTeam,
+            AwayTeam:    m.Fixture.AwayTeam,
+            MatchDate:   m.Fixture.Date,
+            ResultScore: m.Score(),
+            Sets:        scrapedSets(m.SetResults, m.Score()),
+        })
+    }
+    stats.Saved, stats.Scored, stats.Rejected = saveResults(ctx, inputs, "tvf")
+
+    // Rows of tracked leagues without a fixture usually mean a renamed team
+    for _, res := range unmatched {
+        if res.League != "" { stats.Unmatched = append(stats.Unmatched, res.League+": "+res.HomeTeam+" - "+res.AwayTeam) }
+    }
+    return stats, ctx.Err()
+}
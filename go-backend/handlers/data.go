@@ -1,70 +1,168 @@
 package handlers
 
 import (
-    "encoding/json"
-    "os"
-    "path/filepath"
+    "net/url"
+    "strings"
+    "time"
     "github.com/gofiber/fiber/v2"
+
+    "go-backend/league"
+    "go-backend/utils"
 )
 
-// Helper to read JSON data
-func readJSON(filename string) (map[string]interface{}, error) {
-    // Assuming 'data' folder is in the working directory
-    path := filepath.Join("data", filename)
-    
-    // Check if file exists
-    if _, err := os.Stat(path); os.IsNotExist(err) {
-        return nil, err
+// Helper to find a league of the ?season= (default current) in the data store
+func findLeague(c *fiber.Ctx, leagueID string) (*league.League, bool) {
+    return league.Data.LeagueIn(leagueID, c.Query("season"))
+}
+
+// Helper to get every league of the ?season=, by id
+func seasonLeagues(c *fiber.Ctx) map[string]*league.League {
+    return league.Data.Snapshot().Season(c.Query("season"))
+}
+
+// Helper to serve a league from the in-memory data store
+func serveLeague(c *fiber.Ctx, leagueID string, notFound string) error {
+    l, ok := findLeague(c, leagueID)
+    if !ok {
+        return c.Status(404).JSON(fiber.Map{"error": notFound})
     }
+    return c.JSON(l)
+}
 
-    content, err := os.ReadFile(path)
-    if err != nil {
-        return nil, err
+// Helper to load a league's teams and fixture as rating input
+func loadLeague(c *fiber.Ctx, leagueID string) ([]utils.TeamStats, []utils.Match, error) {
+    l, ok := findLeague(c, leagueID)
+    if !ok {
+        return nil, nil, fiber.ErrNotFound
     }
+    teams, matches := l.EloInput()
+    return teams, matches, nil
+}
+
+// Helper to find a team by id, name (any spelling the registry knows) or slug
+func findTeam(leagueID string, teams []utils.TeamStats, param string) (utils.TeamStats, bool) {
+    if unescaped, err := url.PathUnescape(param); err == nil {
+        param = unescaped
+    }
+
+    id := utils.ResolveTeam(league.TeamScope(leagueID), param)
+    slug := utils.Slugify(param)
+    for _, t := range teams {
+        if t.ID == param || t.ID == id || strings.EqualFold(t.Name, param) || utils.Slugify(t.Name) == slug {
+            return t, true
+        }
+    }
+    return utils.TeamStats{}, false
+}
+
+// LeagueVersion is the data version of the league in the :id param, for middleware.Conditional
+func LeagueVersion(c *fiber.Ctx) (string, time.Time, bool) {
+    return league.Data.Version(c.Params("id"), c.Query("season"))
+}
+
+// SnapshotVersion is the data version of all leagues together
+func SnapshotVersion(c *fiber.Ctx) (string, time.Time, bool) {
+    return league.Data.Version("", "")
+}
+
+// GetLeagues lists the registered competitions and whether their data is loaded
+func GetLeagues(c *fiber.Ctx) error {
+    snap := league.Data.Snapshot()
 
-    var data map[string]interface{}
-    if err := json.Unmarshal(content, &data); err != nil {
-        return nil, err
+    leagues := make([]fiber.Map, 0)
+    for _, cfg := range league.Leagues.All() {
+        entry := fiber.Map{"config": cfg, "loaded": false, "seasons": snap.Seasons(cfg.ID)}
+        if l, ok := snap.Leagues[cfg.ID]; ok {
+            entry["loaded"] = true
+            entry["lastUpdated"] = l.LastUpdated
+            entry["teams"] = len(l.Teams)
+            entry["matches"] = len(l.Fixture)
+        }
+        if info, ok := snap.Files[cfg.ID]; ok && info.Error != "" {
+            entry["error"] = info.Error
+        }
+        leagues = append(leagues, entry)
     }
 
-    return data, nil
+    return c.JSON(fiber.Map{"leagues": leagues})
 }
 
-func Get1Lig(c *fiber.Ctx) error {
-    data, err := readJSON("1lig-data.json")
-    if err != nil {
-        return c.Status(404).JSON(fiber.Map{"error": "Data not found"})
+// GetLeague serves the data of any registered league
+func GetLeague(c *fiber.Ctx) error {
+    id := c.Params("id")
+    if _, ok := league.Leagues.Get(id); !ok {
+        return c.Status(404).JSON(fiber.Map{"error": "Unknown league"})
     }
-    
-    // Filter withdrawn teams logic can go here if needed
-    // ...
-    
-    return c.JSON(data)
+    return serveLeague(c, id, "Data not found")
 }
 
-func Get2Lig(c *fiber.Ctx) error {
-    data, err := readJSON("2lig-data.json")
-    if err != nil {
-        return c.Status(404).JSON(fiber.Map{
-            "error": "Cached data not found. Please run scraper script.",
-        })
+// LeagueAlias serves one league under its legacy route (/api/vsl, /api/scrape, ...),
+// in the shape the frontend expects there
+func LeagueAlias(id string) fiber.Handler {
+    cfg, _ := league.Leagues.Get(id)
+    if cfg.APIFormat != league.FormatCEVCup {
+        return func(c *fiber.Ctx) error {
+            return serveLeague(c, id, "Data not found")
+        }
+    }
+
+    return func(c *fiber.Ctx) error {
+        l, ok := findLeague(c, id)
+        if !ok {
+            return c.Status(404).JSON(fiber.Map{"error": "Data not found"})
+        }
+        body, err := l.CEVCupJSON()
+        if err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": "Failed to render league data"})
+        }
+        c.Type("json", "utf-8")
+        return c.Send(body)
     }
-    return c.JSON(data)
 }
 
-func GetVSL(c *fiber.Ctx) error {
-    data, err := readJSON("vsl-data.json")
-    if err != nil {
-         return c.Status(404).JSON(fiber.Map{"error": "Data not found"})
+// AliasVersion is the data version of a league served by LeagueAlias
+func AliasVersion(id string) func(c *fiber.Ctx) (string, time.Time, bool) {
+    return func(c *fiber.Ctx) (string, time.Time, bool) {
+        return league.Data.Version(id, c.Query("season"))
     }
-    return c.JSON(data)
 }
 
-func GetCEVCL(c *fiber.Ctx) error {
-    // Assuming similar structure
-    data, err := readJSON("cev-cl-data.json")
-     if err != nil {
-         return c.Status(404).JSON(fiber.Map{"error": "Data not found"})
+// GetDataStatus reports when the league files were last loaded into memory
+func GetDataStatus(c *fiber.Ctx) error {
+    snap := league.Data.Snapshot()
+
+    leagues := fiber.Map{}
+    for id, info := range snap.Files {
+        entry := fiber.Map{"file": info}
+        if l, ok := snap.Leagues[id]; ok {
+            entry["lastUpdated"] = l.LastUpdated
+            entry["teams"] = len(l.Teams)
+            entry["matches"] = len(l.Fixture)
+        }
+        leagues[id] = entry
     }
-    return c.JSON(data)
+
+    return c.JSON(fiber.Map{
+        "loadedAt": snap.LoadedAt,
+        "leagues":  leagues,
+        "archive":  snap.ArchiveFiles,
+    })
+}
+
+// GetSeasons lists the seasons a league has data for, newest first
+func GetSeasons(c *fiber.Ctx) error {
+    leagueID := c.Params("id")
+    if _, ok := league.Leagues.Get(leagueID); !ok {
+        return c.Status(404).JSON(fiber.Map{"error": "Unknown league"})
+    }
+
+    snap := league.Data.Snapshot()
+    current := ""
+    if l, ok := snap.Leagues[leagueID]; ok { current = l.Season }
+
+    return c.JSON(fiber.Map{
+        "league":  leagueID,
+        "current": current,
+        "seasons": snap.Seasons(leagueID),
+    })
 }
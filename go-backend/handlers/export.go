@@ -0,0 +1,120 @@
+package handlers
+
+import (
+    "bufio"
+    "fmt"
+    "log"
+    "strings"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+const exportSimulations = 1000
+
+// GetExport streams standings, fixtures or simulated rank distributions of a
+// league as CSV or XLSX: /api/export/:dataset?league=vsl&group=...&format=xlsx
+func GetExport(c *fiber.Ctx) error {
+    leagueID := c.Query("league")
+    cfg, ok := league.Leagues.Get(leagueID)
+    l, loaded := findLeague(c, leagueID)
+    if !ok || !loaded {
+        return c.Status(404).JSON(fiber.Map{"error": "League not found"})
+    }
+    group := c.Query("group")
+    format := c.Query("format", "csv")
+    if format != "csv" && format != "xlsx" {
+        return c.Status(400).JSON(fiber.Map{"error": "format must be csv or xlsx"})
+    }
+
+    var table utils.Table
+    switch dataset := c.Params("dataset"); dataset {
+    case "standings":
+        table = standingsTable(l, group)
+    case "fixtures":
+        table = fixturesTable(l, group)
+    case "simulations":
+        if cfg.HasRounds {
+            return c.Status(400).JSON(fiber.Map{"error": "Knockout competitions have no table to simulate"})
+        }
+        table = simulationsTable(cfg, l, group)
+    default:
+        return c.Status(404).JSON(fiber.Map{"error": "Unknown export, use standings, fixtures or simulations"})
+    }
+    if len(table.Rows) == 0 && group != "" {
+        return c.Status(404).JSON(fiber.Map{"error": "Group not found"})
+    }
+
+    filename := utils.Slugify(strings.Join([]string{leagueID, group, c.Params("dataset")}, " ")) + "." + format
+    c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+    write := utils.WriteCSV
+    c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+    if format == "xlsx" {
+        write = utils.WriteXLSX
+        c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+    }
+
+    c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+        if err := write(w, table); err != nil {
+            log.Printf("Export %s failed: %v", filename, err)
+        }
+    })
+    return nil
+}
+
+func standingsTable(l *league.League, group string) utils.Table {
+    rows := make([]league.Standing, 0)
+    for _, g := range l.Standings() {
+        if group == "" || strings.EqualFold(g.Group, group) {
+            rows = append(rows, g.Standings...)
+        }
+    }
+    return utils.TableFromStructs("standings", rows)
+}
+
+func fixturesTable(l *league.League, group string) utils.Table {
+    rows := make([]league.Fixture, 0)
+    for _, f := range l.Fixture {
+        if group == "" || strings.EqualFold(f.GroupName, group) {
+            rows = append(rows, f)
+        }
+    }
+    return utils.TableFromStructs("fixtures", rows)
+}
+
+// simulationsTable runs the Calculate simulation for every group and lists
+// each team's odds and how often it finished in each position (percent)
+func simulationsTable(cfg league.Config, l *league.League, group string) utils.Table {
+    teams, matches := l.EloInput()
+    params := utils.EloParamsFor(cfg.ID)
+
+    oddsTable := utils.TableFromStructs("", []utils.SimulationOdds{})
+    table := utils.Table{Name: "simulations", Columns: append([]string{"teamId", "name", "groupName"}, oddsTable.Columns...)}
+
+    maxTeams := 0
+    for _, g := range l.Standings() {
+        if group != "" && !strings.EqualFold(g.Group, group) { continue }
+
+        groupTeams, groupMatches := groupInput(teams, matches, g.Group, l)
+        sim := utils.SimulateSeason(groupTeams, groupMatches, params, exportSimulations)
+        if len(groupTeams) > maxTeams { maxTeams = len(groupTeams) }
+
+        for _, s := range g.Standings {
+            odds, ok := sim.Odds(s.TeamID, cfg.PlayoffSpots, cfg.RelegationSpots)
+            if !ok { continue }
+            row := []interface{}{s.TeamID, s.Name, s.GroupName}
+            row = append(row, utils.TableFromStructs("", []utils.SimulationOdds{odds}).Rows[0]...)
+            for _, n := range sim.Ranks[s.TeamID] {
+                row = append(row, float64(n)*100/float64(sim.Runs))
+            }
+            table.Rows = append(table.Rows, row)
+        }
+    }
+
+    for rank := 1; rank <= maxTeams; rank++ {
+        table.Columns = append(table.Columns, fmt.Sprintf("rank%d", rank))
+    }
+    return table
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+    "sort"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+type Meeting struct {
+    League     string           `json:"league"`
+    Season     string           `json:"season,omitempty"`
+    Round      string           `json:"round,omitempty"`
+    Week       int              `json:"week,omitempty"`
+    Date       string           `json:"date,omitempty"`
+    HomeTeam   string           `json:"homeTeam"`
+    AwayTeam   string           `json:"awayTeam"`
+    HomeTeamID string           `json:"homeTeamId"`
+    AwayTeamID string           `json:"awayTeamId"`
+    Score      string           `json:"score,omitempty"`
+    Sets       []utils.SetScore `json:"sets"`
+    WinnerID   string           `json:"winnerId,omitempty"`
+    IsPlayed   bool             `json:"isPlayed"`
+}
+
+// GetHeadToHead lists every meeting of two teams in the loaded competitions.
+// Wins and margins are from the point of view of the ?home= team, wherever
+// the matches were played.
+func GetHeadToHead(c *fiber.Ctx) error {
+    homeParam, awayParam := c.Query("home"), c.Query("away")
+    if homeParam == "" || awayParam == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "home and away are required"})
+    }
+
+    // One season with ?season=, otherwise the current files and every archived season
+    snap := league.Data.Snapshot()
+    seasons := []map[string]*league.League{seasonLeagues(c)}
+    if c.Query("season") == "" {
+        for _, archived := range snap.Archive {
+            seasons = append(seasons, archived)
+        }
+    }
+    configs := league.Leagues.All()
+
+    homeID, candidates := resolveTeamID(seasons, configs, homeParam)
+    if homeID == "" {
+        return teamNotFound(c, candidates)
+    }
+    awayID, candidates := resolveTeamID(seasons, configs, awayParam)
+    if awayID == "" {
+        return teamNotFound(c, candidates)
+    }
+
+    // Leagues sharing a team scope (2lig and its tvf copy) list the same
+    // matches, so meetings are keyed by date and teams
+    seen := make(map[string]bool)
+    meetings := make([]Meeting, 0)
+    for _, leagues := range seasons {
+        for _, cfg := range configs {
+            l, ok := leagues[cfg.ID]
+            if !ok { continue }
+            for _, f := range l.Fixture {
+                forward := f.HomeTeamID == homeID && f.AwayTeamID == awayID
+                reverse := f.HomeTeamID == awayID && f.AwayTeamID == homeID
+                if !forward && !reverse || f.GoldenSet { continue }
+
+                key := f.Date + "|" + f.HomeTeamID + "|" + f.AwayTeamID
+                if f.Date != "" && seen[key] { continue }
+                seen[key] = true
+
+                m := Meeting{
+                    League:     cfg.ID,
+                    Season:     l.Season,
+                    Round:      f.Round,
+                    Week:       f.Week,
+                    Date:       f.Date,
+                    HomeTeam:   f.HomeTeam,
+                    AwayTeam:   f.AwayTeam,
+                    HomeTeamID: f.HomeTeamID,
+                    AwayTeamID: f.AwayTeamID,
+                    Score:      f.Score(),
+                    Sets:       utils.ParseSets(f.SetResults),
+                    IsPlayed:   f.IsPlayed,
+                }
+                if m.Sets == nil { m.Sets = []utils.SetScore{} }
+                if f.IsPlayed && f.HomeScore != nil && f.AwayScore != nil {
+                    m.WinnerID = f.HomeTeamID
+                    if *f.AwayScore > *f.HomeScore { m.WinnerID = f.AwayTeamID }
+                }
+                meetings = append(meetings, m)
+            }
+        }
+    }
+    sort.SliceStable(meetings, func(i, j int) bool { return meetings[i].Date < meetings[j].Date })
+
+    var played, homeWins, awayWins, setDiff, pointDiff, setsWithPoints int
+    var lastMeeting, nextMeeting *Meeting
+    for i := range meetings {
+        m := &meetings[i]
+        if !m.IsPlayed {
+            if nextMeeting == nil { nextMeeting = m }
+            continue
+        }
+        hSets, aSets, ok := utils.ParseScore(m.Score)
+        if !ok { continue }
+
+        // flip to the ?home= team's point of view
+        flip := m.HomeTeamID != homeID
+        if flip { hSets, aSets = aSets, hSets }
+
+        played++
+        setDiff += hSets - aSets
+        if hSets > aSets { homeWins++ } else { awayWins++ }
+        for _, s := range m.Sets {
+            if flip { pointDiff += s.Away - s.Home } else { pointDiff += s.Home - s.Away }
+            setsWithPoints++
+        }
+        lastMeeting = m
+    }
+
+    result := fiber.Map{
+        "home":               teamRef(seasons, homeID),
+        "away":               teamRef(seasons, awayID),
+        "played":             played,
+        "homeWins":           homeWins,
+        "awayWins":           awayWins,
+        "averageSetMargin":   0.0, // sets won minus lost per meeting
+        "averagePointMargin": 0.0, // rally points per set, from meetings with set scores
+        "lastMeeting":        lastMeeting,
+        "nextMeeting":        nextMeeting,
+        "meetings":           meetings,
+    }
+    if played > 0 { result["averageSetMargin"] = float64(setDiff) / float64(played) }
+    if setsWithPoints > 0 { result["averagePointMargin"] = float64(pointDiff) / float64(setsWithPoints) }
+
+    return c.JSON(result)
+}
+
+// teamRef names a team found by resolveTeamID, preferring the registry name
+func teamRef(seasons []map[string]*league.League, id string) fiber.Map {
+    name := ""
+    if registered, ok := utils.Teams().Lookup(id); ok {
+        name = registered.Name
+    }
+    for _, leagues := range seasons {
+        for _, cfg := range league.Leagues.All() {
+            if l, ok := leagues[cfg.ID]; ok {
+                for _, t := range l.Teams {
+                    if t.ID == id && name == "" { name = t.Name }
+                }
+            }
+        }
+    }
+    return fiber.Map{"teamId": id, "name": name}
+}
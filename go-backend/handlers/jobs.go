@@ -0,0 +1,399 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/database"
+    "go-backend/league"
+    "go-backend/scheduler"
+    "go-backend/scraper"
+    "go-backend/utils"
+)
+
+// JobFuncs are the jobs config/jobs.json can schedule
+func JobFuncs() map[string]scheduler.Func {
+    // The score job looks at corrections recorded from now on, older ones
+    // were for the process that recorded them
+    if changes := league.Data.Changes(); changes != nil {
+        scoreCursor = changes.LastID()
+    }
+    return map[string]scheduler.Func{
+        "scrape-tvf":  scrapeTVFJob,
+        "scrape-cev":  scrapeCEVJob,
+        "sync":        syncJob,
+        "score":       scoreJob,
+        "leaderboard": leaderboardJob,
+        "notify":      notifyJob,
+    }
+}
+
+func scrapeTVFJob(ctx context.Context) (string, error) {
+    stats, err := scrapeTVFResults(ctx)
+    return stats.summary(), err
+}
+
+// scrapeCEVJob rewrites the data files of the active CEV competitions, the
+// store picks the new files up on its next check
+func scrapeCEVJob(ctx context.Context) (string, error) {
+    ids := make([]string, 0, len(scraper.CEVCompetitions))
+    for id := range scraper.CEVCompetitions {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    var written, failed []string
+    for _, id := range ids {
+        if ctx.Err() != nil { return "", ctx.Err() }
+        cfg, ok := league.Leagues.Get(id)
+        path, known := league.Data.Path(id)
+        if !ok || !known || !cfg.IsActive { continue }
+
+        season := ""
+        if current, ok := league.Data.League(id); ok { season = current.Season }
+        url, _ := scraper.CEVURL(id, season)
+        l, err := scraper.FetchCEV(ctx, id, url)
+        if err == nil {
+            l.Season = season
+            err = scraper.WriteLeague(path, l)
+        }
+        if err != nil {
+            failed = append(failed, fmt.Sprintf("%s: %v", id, err))
+            continue
+        }
+        written = append(written, id)
+    }
+
+    summary := fmt.Sprintf("wrote %v", written)
+    if len(failed) > 0 {
+        return summary, fmt.Errorf("%d competitions failed: %v", len(failed), failed)
+    }
+    return summary, nil
+}
+
+// syncJob writes the active leagues into the league tables like the sync command
+func syncJob(ctx context.Context) (string, error) {
+    inserts, updates, deletes := 0, 0, 0
+    var failed []string
+    for _, cfg := range league.Leagues.All() {
+        if ctx.Err() != nil { return "", ctx.Err() }
+        l, ok := league.Data.League(cfg.ID)
+        if !cfg.IsActive || !ok { continue }
+
+        plan, err := league.Sync(repos.Leagues, cfg, l, false)
+        if err != nil {
+            failed = append(failed, fmt.Sprintf("%s: %v", cfg.ID, err))
+        }
+        if plan != nil {
+            inserts += plan.Count(league.SyncInsert)
+            updates += plan.Count(league.SyncUpdate)
+            deletes += plan.Count(league.SyncDelete)
+        }
+    }
+
+    summary := fmt.Sprintf("%d inserts, %d updates, %d deletes", inserts, updates, deletes)
+    if len(failed) > 0 {
+        return summary, fmt.Errorf("%d leagues failed: %v", len(failed), failed)
+    }
+    return summary, nil
+}
+
+// scoreCursor is the last changelog entry the score job has looked at,
+// the newest one at startup
+var scoreCursor int
+
+// scoreJob scores predictions whose match has a result in the league files
+// but never came through the results sync, e.g. after a manual file update,
+// and rescores matches whose result a data file corrected
+func scoreJob(ctx context.Context) (string, error) {
+    corrected, err := rescoreCorrections(ctx)
+    if err != nil {
+        return "", err
+    }
+
+    pending, err := repos.Predictions.PendingMatchIDs()
+    if err != nil {
+        return "", err
+    }
+    if len(pending) == 0 {
+        return fmt.Sprintf("no predictions waiting, %d corrected matches rescored", corrected), nil
+    }
+
+    wanted := make(map[string]bool, len(pending))
+    for _, id := range pending {
+        wanted[id] = true
+    }
+    var inputs []ResultInput
+    for id, l := range league.Data.Snapshot().Leagues {
+        for _, f := range l.Fixture {
+            matchID := f.MatchID()
+            if !wanted[matchID] || !f.IsPlayed || f.GoldenSet || f.Score() == "" { continue }
+            inputs = append(inputs, ResultInput{
+                MatchID:     matchID,
+                League:      id,
+                GroupName:   f.GroupName,
+                HomeTeam:    f.HomeTeam,
+                AwayTeam:    f.AwayTeam,
+                MatchDate:   f.Date,
+                ResultScore: f.Score(),
+                Sets:        scrapedSets(f.SetResults, f.Score()),
+            })
+        }
+    }
+
+    saved, scored, _ := saveResults(ctx, inputs, "file")
+    if err := ctx.Err(); err != nil {
+        return fmt.Sprintf("%d results saved before the timeout", saved), err
+    }
+    return fmt.Sprintf("%d matches waiting, %d results saved, %d predictions scored, %d corrected matches rescored", len(pending), saved, scored, corrected), nil
+}
+
+// rescoreCorrections saves and rescores the results data files corrected
+// since the last run, unless a newer result replaced them. Ingested
+// corrections were rescored when they came in.
+func rescoreCorrections(ctx context.Context) (int, error) {
+    changes := league.Data.Changes()
+    if changes == nil {
+        return 0, nil
+    }
+
+    corrections, err := changes.Changes(league.ChangeFilter{AfterID: scoreCursor, Kind: league.ChangeCorrection})
+    if err != nil {
+        return 0, err
+    }
+    rescored := 0
+    for _, c := range corrections {
+        if err := ctx.Err(); err != nil {
+            return rescored, err
+        }
+        // A result ingested after the correction is newer than the file's
+        if c.Source == "file" && c.Before.Score != c.After.Score && changes.Latest(c) {
+            homeSets, awaySets := utils.SplitSets(scrapedSets(c.After.SetResults, c.After.Score))
+            _, err := saveScoredResult(database.MatchResult{
+                MatchID:     c.MatchID,
+                League:      c.League,
+                HomeTeam:    c.HomeTeam,
+                AwayTeam:    c.AwayTeam,
+                MatchDate:   optionalDate(c.After.Date),
+                ResultScore: c.After.Score,
+                HomeSets:    homeSets,
+                AwaySets:    awaySets,
+                IsVerified:  true,
+            })
+            if err != nil {
+                return rescored, fmt.Errorf("rescoring %s (%s): %w", c.MatchID, c.League, err)
+            }
+            rescored++
+        }
+        scoreCursor = c.ID
+    }
+    return rescored, nil
+}
+
+// leaderboardJob recomputes every user's leaderboard counters from their
+// scored predictions. Weekly points count from Monday, monthly from the first
+// of the month, both in Turkey time and by when the prediction was scored.
+func leaderboardJob(ctx context.Context) (string, error) {
+    predictions, err := repos.Predictions.ScoredPredictions()
+    if err != nil {
+        return "", err
+    }
+
+    now := time.Now().In(utils.TurkeyTime)
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.TurkeyTime)
+    weekStart := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+    monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, utils.TurkeyTime)
+
+    byUser := make(map[string][]database.Prediction)
+    for _, p := range predictions {
+        byUser[p.UserID] = append(byUser[p.UserID], p)
+    }
+
+    entries := make([]database.LeaderboardEntry, 0, len(byUser))
+    for userID, preds := range byUser {
+        // Streaks run in match order
+        sort.SliceStable(preds, func(i, j int) bool {
+            a, b := "", ""
+            if preds[i].MatchDate != nil { a = *preds[i].MatchDate }
+            if preds[j].MatchDate != nil { b = *preds[j].MatchDate }
+            if a != b { return a < b }
+            return preds[i].UpdatedAt < preds[j].UpdatedAt
+        })
+
+        e := database.LeaderboardEntry{UserID: userID}
+        for _, p := range preds {
+            e.TotalPoints += p.PointsEarned
+            e.TotalPredictions++
+            switch p.PointsEarned {
+            case SCORE_EXACT_MATCH:
+                e.CorrectPredictions++
+            case SCORE_WINNER_CORRECT:
+                e.PartialPredictions++
+            }
+            if p.PointsEarned > 0 {
+                e.CurrentStreak++
+                if e.CurrentStreak > e.BestStreak { e.BestStreak = e.CurrentStreak }
+            } else {
+                e.CurrentStreak = 0
+            }
+            if scored, err := time.Parse(time.RFC3339Nano, p.UpdatedAt); err == nil {
+                if !scored.Before(weekStart) { e.WeeklyPoints += p.PointsEarned }
+                if !scored.Before(monthStart) { e.MonthlyPoints += p.PointsEarned }
+            }
+        }
+        entries = append(entries, e)
+    }
+
+    if err := ctx.Err(); err != nil {
+        return "", err
+    }
+    if err := repos.Leaderboard.SaveLeaderboardStats(entries); err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%d users from %d scored predictions", len(entries), len(predictions)), nil
+}
+
+// ResultNotification tells the webhook a result scored predictions
+type ResultNotification struct {
+    MatchID           string    `json:"matchId"`
+    League            string    `json:"league"`
+    HomeTeam          string    `json:"homeTeam"`
+    AwayTeam          string    `json:"awayTeam"`
+    ResultScore       string    `json:"resultScore"`
+    ScoredPredictions int       `json:"scoredPredictions"`
+    At                time.Time `json:"at"`
+}
+
+// Notifications wait here until the notify job delivers them
+var (
+    outboxMu sync.Mutex
+    outbox   []ResultNotification
+)
+
+const outboxSize = 500
+
+func queueNotification(res ResultInput, scored int) {
+    outboxMu.Lock()
+    defer outboxMu.Unlock()
+    outbox = append(outbox, ResultNotification{res.MatchID, res.League, res.HomeTeam, res.AwayTeam, res.ResultScore, scored, time.Now()})
+    if len(outbox) > outboxSize { outbox = outbox[len(outbox)-outboxSize:] }
+}
+
+// notifyJob posts the queued result notifications to NOTIFY_WEBHOOK_URL,
+// which fans them out as push messages. Failed deliveries stay queued.
+func notifyJob(ctx context.Context) (string, error) {
+    outboxMu.Lock()
+    events := outbox
+    outbox = nil
+    outboxMu.Unlock()
+
+    if len(events) == 0 {
+        return "nothing to send", nil
+    }
+    url := os.Getenv("NOTIFY_WEBHOOK_URL")
+    if url == "" {
+        return fmt.Sprintf("NOTIFY_WEBHOOK_URL not set, dropped %d notifications", len(events)), nil
+    }
+
+    err := postNotifications(ctx, url, events)
+    if err != nil {
+        outboxMu.Lock()
+        outbox = append(events, outbox...)
+        if len(outbox) > outboxSize { outbox = outbox[len(outbox)-outboxSize:] }
+        outboxMu.Unlock()
+        return "", err
+    }
+    return fmt.Sprintf("sent %d notifications", len(events)), nil
+}
+
+func postNotifications(ctx context.Context, url string, events []ResultNotification) error {
+    body, err := json.Marshal(fiber.Map{"type": "match_result", "events": events})
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    if secret := os.Getenv("CRON_SECRET"); secret != "" { req.Header.Set("Authorization", "Bearer "+secret) }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        return fmt.Errorf("webhook answered %s", resp.Status)
+    }
+    return nil
+}
+
+// Admin endpoints
+
+func jobError(c *fiber.Ctx, err error) error {
+    switch {
+    case errors.Is(err, scheduler.ErrUnknownJob):
+        return c.Status(404).JSON(fiber.Map{"error": "Job not found"})
+    case errors.Is(err, scheduler.ErrRunning), errors.Is(err, scheduler.ErrLocked):
+        return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+    }
+    return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+func schedulerOff(c *fiber.Ctx) error {
+    return c.Status(503).JSON(fiber.Map{"error": "Jobs are not loaded"})
+}
+
+func GetJobs(c *fiber.Ctx) error {
+    if scheduler.Jobs == nil { return schedulerOff(c) }
+    // scheduled is false unless SCHEDULER=on, the jobs then only run when triggered
+    return c.JSON(fiber.Map{"jobs": scheduler.Jobs.List(), "scheduled": scheduler.Jobs.Started()})
+}
+
+func GetJobRuns(c *fiber.Ctx) error {
+    if scheduler.Jobs == nil { return schedulerOff(c) }
+    runs, err := scheduler.Jobs.History(c.Params("name"), c.QueryInt("limit", 50))
+    if err != nil {
+        return jobError(c, err)
+    }
+    // Without a historyFile the runs are only those since the last start
+    return c.JSON(fiber.Map{"runs": runs, "historyPersisted": scheduler.Jobs.PersistentHistory()})
+}
+
+func TriggerJob(c *fiber.Ctx) error {
+    if scheduler.Jobs == nil { return schedulerOff(c) }
+    run, err := scheduler.Jobs.Trigger(c.Params("name"))
+    if err != nil {
+        if errors.Is(err, scheduler.ErrRunning) {
+            return c.Status(409).JSON(fiber.Map{"error": err.Error(), "run": run})
+        }
+        return jobError(c, err)
+    }
+    return c.Status(202).JSON(fiber.Map{"run": run})
+}
+
+func PauseJob(c *fiber.Ctx) error {
+    return setJobPaused(c, true)
+}
+
+func ResumeJob(c *fiber.Ctx) error {
+    return setJobPaused(c, false)
+}
+
+func setJobPaused(c *fiber.Ctx, paused bool) error {
+    if scheduler.Jobs == nil { return schedulerOff(c) }
+    if err := scheduler.Jobs.SetPaused(c.Params("name"), paused); err != nil {
+        return jobError(c, err)
+    }
+    return c.JSON(fiber.Map{"success": true, "name": c.Params("name"), "paused": paused})
+}
@@ -1,93 +1,59 @@
 package handlers
 
 import (
-    "fmt"
     "strconv"
     "github.com/gofiber/fiber/v2"
     "go-backend/database"
-
-    "github.com/supabase-community/postgrest-go"
 )
 
+// RankedEntry is a leaderboard row with its position for the requested period
+type RankedEntry struct {
+    database.LeaderboardEntry
+    Rank int `json:"rank"`
+}
+
 func GetLeaderboard(c *fiber.Ctx) error {
     limitStr := c.Query("limit", "50")
     typeStr := c.Query("type", "total")
-    
+
     limit, _ := strconv.Atoi(limitStr)
-    
-    orderColumn := "total_points"
-    if typeStr == "weekly" { orderColumn = "weekly_points" }
-    if typeStr == "monthly" { orderColumn = "monthly_points" }
 
     // Fetch leaderboard
-    var leaderboard []map[string]interface{}
-    _, err := database.Client.From("leaderboard").
-        Select("*", "", false).
-        Order(orderColumn, &postgrest.OrderOpts{Ascending: false}).
-        Limit(limit, "").
-        ExecuteTo(&leaderboard)
-
+    leaderboard, err := repos.Leaderboard.Leaderboard(typeStr, limit)
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
 
-    // Add rank
-    // In Go we iterate and add fields, but map is reference type so it's fine
-    // However, if we want to add "rank", we might need to copy specific fields or just use the index
     // The previous TS implementation returned "rank" index+1
-    // We can do this client side or server side. Let's do server side.
-    
-    rankedData := make([]map[string]interface{}, len(leaderboard))
+    rankedData := make([]RankedEntry, len(leaderboard))
     for i, entry := range leaderboard {
-        newEntry := make(map[string]interface{})
-        for k, v := range entry {
-            newEntry[k] = v
-        }
-        newEntry["rank"] = i + 1
-        rankedData[i] = newEntry
+        rankedData[i] = RankedEntry{entry, i + 1}
     }
 
     // Handle user specific data if logged in
-    var userEntry map[string]interface{}
+    var userEntry *RankedEntry
     var userRank int = 0
-    
+
     // We can check authorization header manually without strictly requiring it
     userID, ok := c.Locals("userID").(string)
     if ok && userID != "" {
         // Find user in fetched leaderboard
-        found := false
-        for _, entry := range rankedData {
-            if entry["user_id"] == userID {
-                userEntry = entry
-                userRank = entry["rank"].(int)
-                found = true
+        for i := range rankedData {
+            if rankedData[i].UserID == userID {
+                userEntry = &rankedData[i]
+                userRank = rankedData[i].Rank
                 break
             }
         }
 
-        // If not found, fetch specifically
-        if !found {
-            var userData []map[string]interface{}
-            _, err := database.Client.From("leaderboard").
-                 Select("*", "", false).
-                 Eq("user_id", userID).
-                 ExecuteTo(&userData)
-            
-            if len(userData) > 0 && err == nil {
-                // Determine rank (count of people with more points)
-                 points := userData[0][orderColumn].(float64) // JSON numbers are float64 by default in unmarshal
-                 
-                 _, _, _ = database.Client.From("leaderboard").
-                     Select("user_id", "exact", false).
-                     Gt(orderColumn, fmt.Sprintf("%f", points)).
-                     Execute()
-                 
-                 // Note: supabase-go might not return count easily in this version without 'count' param
-                 // For now, let's skip complex rank calc if not in top list purely for speed in this migration scaffold
-                 userRank = int(len(leaderboard)) + 1 // Approximation or need count query
-                 
-                 userEntry = userData[0]
-                 userEntry["rank"] = userRank // Mock or fetched
+        // If not found, fetch specifically and count the people with more points
+        if userEntry == nil {
+            entry, err := repos.Leaderboard.LeaderboardEntry(userID)
+            if entry != nil && err == nil {
+                if rank, err := repos.Leaderboard.LeaderboardRank(typeStr, entry.Points(typeStr)); err == nil {
+                    userRank = rank
+                    userEntry = &RankedEntry{*entry, rank}
+                }
             }
         }
     }
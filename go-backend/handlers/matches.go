@@ -0,0 +1,95 @@
+package handlers
+
+import (
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/league"
+)
+
+const (
+    defaultMatchLimit = 50
+    maxMatchLimit     = 200
+)
+
+// GetMatches serves a filtered, sorted and paginated view of a league's fixture.
+// Query: team, group, week, round, from, to, played, venue, city,
+// sort (date|week|round|matchNo, "-" prefix for descending), limit, cursor.
+func GetMatches(c *fiber.Ctx) error {
+    leagueID := c.Params("id")
+
+    l, ok := findLeague(c, leagueID)
+    if !ok {
+        return c.Status(404).JSON(fiber.Map{"error": "League not found"})
+    }
+    teams, _ := l.EloInput()
+
+    q := league.MatchQuery{
+        Group:  c.Query("group"),
+        Round:  c.Query("round"),
+        From:   c.Query("from"),
+        To:     c.Query("to"),
+        Venue:  c.Query("venue"),
+        City:   c.Query("city"),
+        Cursor: c.Query("cursor"),
+        Limit:  c.QueryInt("limit", defaultMatchLimit),
+    }
+
+    if teamParam := c.Query("team"); teamParam != "" {
+        team, ok := findTeam(leagueID, teams, teamParam)
+        if !ok {
+            return c.Status(404).JSON(fiber.Map{"error": "Team not found"})
+        }
+        q.TeamID = team.ID
+    }
+
+    if week := c.Query("week"); week != "" {
+        n, err := strconv.Atoi(week)
+        if err != nil || n < 1 {
+            return c.Status(400).JSON(fiber.Map{"error": "week must be a positive number"})
+        }
+        q.Week = n
+    }
+
+    for _, date := range []string{q.From, q.To} {
+        if date == "" { continue }
+        if _, err := time.Parse("2006-01-02", date); err != nil {
+            return c.Status(400).JSON(fiber.Map{"error": "from and to must be YYYY-MM-DD"})
+        }
+    }
+
+    if played := c.Query("played"); played != "" {
+        v, err := strconv.ParseBool(played)
+        if err != nil {
+            return c.Status(400).JSON(fiber.Map{"error": "played must be true or false"})
+        }
+        q.Played = &v
+    }
+
+    q.Sort = c.Query("sort", "date")
+    if strings.HasPrefix(q.Sort, "-") {
+        q.Sort, q.Desc = q.Sort[1:], true
+    }
+    if !league.ValidSort(q.Sort) {
+        return c.Status(400).JSON(fiber.Map{"error": "sort must be one of date, week, round, matchNo"})
+    }
+
+    if q.Limit < 1 || q.Limit > maxMatchLimit {
+        return c.Status(400).JSON(fiber.Map{"error": "limit must be between 1 and " + strconv.Itoa(maxMatchLimit)})
+    }
+
+    page, err := l.Matches(q)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.JSON(fiber.Map{
+        "league":     leagueID,
+        "total":      page.Total,
+        "count":      len(page.Matches),
+        "matches":    page.Matches,
+        "nextCursor": page.NextCursor,
+    })
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+    "strings"
+    "github.com/gofiber/fiber/v2"
+    "go-backend/utils"
+)
+
+// Competitions merged into the power rankings unless ?leagues= says otherwise
+const defaultPowerLeagues = "vsl,1lig,cev-cl,cev-cup,cev-challenge"
+
+func GetPowerRankings(c *fiber.Ctx) error {
+    leagueIDs := strings.Split(c.Query("leagues", defaultPowerLeagues), ",")
+
+    competitions := make([]utils.Competition, 0, len(leagueIDs))
+    for _, id := range leagueIDs {
+        id = strings.TrimSpace(id)
+        teams, matches, err := loadLeague(c, id)
+        if err != nil {
+            return c.Status(404).JSON(fiber.Map{"error": "League not found: " + id})
+        }
+        competitions = append(competitions, utils.Competition{League: id, Teams: teams, Matches: matches})
+    }
+
+    rankings := utils.CalculatePowerRankings(competitions, utils.DefaultLeagueOffsets, utils.EloParamsFor(""))
+    return c.JSON(rankings)
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-    "math"
     "github.com/gofiber/fiber/v2"
+    "go-backend/league"
     "go-backend/utils"
 )
 
@@ -10,6 +10,7 @@ type PredictAllRequest struct {
     Teams           []utils.TeamStats `json:"teams"`
     UpcomingMatches []utils.Match     `json:"upcomingMatches"`
     AllMatches      []utils.Match     `json:"allMatches"`
+    League          string            `json:"league"` // optional, selects fitted Elo parameters
 }
 
 func PredictAll(c *fiber.Ctx) error {
@@ -22,16 +23,23 @@ func PredictAll(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "Missing match data"})
     }
 
-    eloMap := utils.CalculateElo(req.Teams, req.AllMatches)
+    // Ratings follow stable team ids when the league is known
+    if req.League != "" {
+        utils.ResolveTeamIDs(league.TeamScope(req.League), req.Teams, req.AllMatches)
+        utils.ResolveTeamIDs(league.TeamScope(req.League), nil, req.UpcomingMatches)
+    }
+
+    params := utils.EloParamsFor(req.League)
+    eloMap := utils.CalculateEloWithParams(req.Teams, req.AllMatches, params)
     predictions := make(map[string]string)
 
     for _, m := range req.UpcomingMatches {
-        hElo := eloMap[m.HomeTeam]
-        if hElo == 0 { hElo = 1200 }
-        aElo := eloMap[m.AwayTeam]
-        if aElo == 0 { aElo = 1200 }
+        hElo := eloMap[m.HomeKey()]
+        if hElo == 0 { hElo = utils.InitialElo }
+        aElo := eloMap[m.AwayKey()]
+        if aElo == 0 { aElo = utils.InitialElo }
 
-        expectedHome := 1.0 / (1.0 + math.Pow(10, (aElo-hElo)/400.0))
+        expectedHome := params.Expected(hElo, aElo)
         score := "3-2"
 
         if expectedHome > 0.85 {
@@ -3,8 +3,6 @@ package handlers
 import (
     "github.com/gofiber/fiber/v2"
     "go-backend/database"
-
-    "github.com/supabase-community/postgrest-go"
 )
 
 type PredictionInput struct {
@@ -19,35 +17,16 @@ type PredictionInput struct {
 
 func GetPredictions(c *fiber.Ctx) error {
     userID := c.Locals("userID").(string)
-    league := c.Query("league")
-    group := c.Query("group")
-
-    // Construct query
-    query := database.Client.From("predictions").
-        Select("*", "", false).
-        Eq("user_id", userID).
-        Order("created_at", &postgrest.OrderOpts{Ascending: false})
-    
-    if league != "" {
-        query = query.Eq("league", league)
-    }
-    if group != "" {
-        query = query.Eq("group_name", group)
-    }
 
-    // Execute
-    // Note: supabase-go might return json string or map, depending on usage.
-    // ExecuteTo is useful to map to struct. 
-    // For now we assume a raw Execute is fine or we define a struct.
-    // Let's use a generic map for flexibility in this migration phase.
-    var results []map[string]interface{}
-    _, err := query.ExecuteTo(&results)
-    
+    predictions, err := repos.Predictions.ListPredictions(userID, database.PredictionFilter{
+        League: c.Query("league"),
+        Group:  c.Query("group"),
+    })
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
     
-    return c.JSON(fiber.Map{"predictions": results})
+    return c.JSON(fiber.Map{"predictions": predictions})
 }
 
 func SavePredictions(c *fiber.Ctx) error {
@@ -71,36 +50,27 @@ func SavePredictions(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "No predictions provided"})
     }
 
-    // Prepare data for upsert
-    var records []map[string]interface{}
+    records := make([]database.Prediction, 0, len(input))
     for _, p := range input {
-        // Need to convert matchDate if present
-        // Default to nil or string logic
-        var mDate interface{} = nil
+        var matchDate *string
         if p.MatchDate != "" {
-            mDate = p.MatchDate // Assuming format is correct (YYYY-MM-DD or ISO)
+            date := p.MatchDate // Assuming format is correct (YYYY-MM-DD or ISO)
+            matchDate = &date
         }
 
-        records = append(records, map[string]interface{}{
-            "user_id": userID,
-            "match_id": p.MatchID,
-            "league": p.League,
-            "group_name": p.GroupName,
-            "home_team": p.HomeTeam,
-            "away_team": p.AwayTeam,
-            "match_date": mDate,
-            "predicted_score": p.PredictedScore,
-            // "updated_at": time.Now()... // Supabase might handle this or we send it
+        records = append(records, database.Prediction{
+            UserID:         userID,
+            MatchID:        p.MatchID,
+            League:         p.League,
+            GroupName:      p.GroupName,
+            HomeTeam:       p.HomeTeam,
+            AwayTeam:       p.AwayTeam,
+            MatchDate:      matchDate,
+            PredictedScore: p.PredictedScore,
         })
     }
 
-    // Upsert
-    // Note: supabase-go Upsert syntax
-    _, _, err := database.Client.From("predictions").
-        Upsert(records, "", "", "").
-        Execute()
-
-    if err != nil {
+    if err := repos.Predictions.SavePredictions(records); err != nil {
          return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
 
@@ -119,13 +89,7 @@ func DeletePrediction(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "Match ID required"})
     }
 
-    _, _, err := database.Client.From("predictions").
-        Delete("", "").
-        Eq("user_id", userID).
-        Eq("match_id", matchID).
-        Execute()
-
-    if err != nil {
+    if err := repos.Predictions.DeletePrediction(userID, matchID); err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
 
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+    "github.com/gofiber/fiber/v2"
+    "go-backend/utils"
+)
+
+// GetTeamRatings returns a team's rating after every played match of the season
+func GetTeamRatings(c *fiber.Ctx) error {
+    leagueID := c.Params("id")
+
+    teams, matches, err := loadLeague(c, leagueID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "League not found"})
+    }
+
+    team, ok := findTeam(leagueID, teams, c.Params("team"))
+    if !ok {
+        return c.Status(404).JSON(fiber.Map{"error": "Team not found"})
+    }
+
+    params := utils.EloParamsFor(leagueID)
+    ratings, history := utils.CalculateEloHistory(teams, matches, params)
+
+    series := history[team.Key()]
+    if series == nil {
+        series = []utils.RatingPoint{}
+    }
+
+    return c.JSON(fiber.Map{
+        "league":  leagueID,
+        "teamId":  team.ID,
+        "team":    team.Name,
+        "rating":  ratings[team.Key()],
+        "initial": utils.InitialElo,
+        "history": series,
+    })
+}
@@ -0,0 +1,11 @@
+package handlers
+
+import "go-backend/database"
+
+// repos is where the handlers read and write user data, set once at startup
+var repos *database.Repositories
+
+// Init hands the handlers their repositories
+func Init(r *database.Repositories) {
+    repos = r
+}
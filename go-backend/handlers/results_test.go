@@ -0,0 +1,173 @@
+package handlers
+
+import (
+    "bytes"
+    "encoding/json"
+    "log"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/database"
+    "go-backend/league"
+)
+
+// testApp serves the handlers on the in-memory backend, signed in as user
+func testApp(t *testing.T, user string) (*fiber.App, *database.Memory) {
+    t.Helper()
+    memory := database.NewMemory()
+    Init(memory.Repositories())
+    league.Data = league.NewStore(t.TempDir(), nil)
+    t.Setenv("CRON_SECRET", "cron")
+
+    app := fiber.New()
+    app.Post("/api/results/sync", SyncResults)
+    signedIn := func(c *fiber.Ctx) error {
+        c.Locals("userID", user)
+        return c.Next()
+    }
+    app.Get("/api/predictions", signedIn, GetPredictions)
+    app.Post("/api/predictions", signedIn, SavePredictions)
+    app.Delete("/api/predictions", signedIn, DeletePrediction)
+    return app, memory
+}
+
+func request(t *testing.T, app *fiber.App, method, url, body string, headers ...string) (int, map[string]interface{}) {
+    t.Helper()
+    req := httptest.NewRequest(method, url, strings.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    for i := 0; i+1 < len(headers); i += 2 {
+        req.Header.Set(headers[i], headers[i+1])
+    }
+    resp, err := app.Test(req, -1)
+    if err != nil {
+        t.Fatalf("%s %s: %v", method, url, err)
+    }
+    defer resp.Body.Close()
+
+    var decoded map[string]interface{}
+    json.NewDecoder(resp.Body).Decode(&decoded)
+    return resp.StatusCode, decoded
+}
+
+func TestPredictionsRoundTrip(t *testing.T) {
+    app, _ := testApp(t, "user-1")
+
+    status, body := request(t, app, "POST", "/api/predictions",
+        `[{"matchId":"A-B","league":"vsl","homeTeam":"A","awayTeam":"B","predictedScore":"3-1"},
+          {"matchId":"C-D","league":"1lig","homeTeam":"C","awayTeam":"D","predictedScore":"0-3"}]`)
+    if status != 200 || body["saved"] != 2.0 {
+        t.Fatalf("save: %d %v", status, body)
+    }
+
+    // Saving the same match again replaces the prediction
+    request(t, app, "POST", "/api/predictions", `{"matchId":"A-B","league":"vsl","homeTeam":"A","awayTeam":"B","predictedScore":"3-0"}`)
+
+    status, body = request(t, app, "GET", "/api/predictions?league=vsl", "")
+    predictions, _ := body["predictions"].([]interface{})
+    if status != 200 || len(predictions) != 1 {
+        t.Fatalf("list: %d %v", status, body)
+    }
+    if got := predictions[0].(map[string]interface{})["predicted_score"]; got != "3-0" {
+        t.Errorf("predicted_score = %v, want 3-0", got)
+    }
+
+    request(t, app, "DELETE", "/api/predictions?matchId=A-B", "")
+    _, body = request(t, app, "GET", "/api/predictions", "")
+    if predictions, _ := body["predictions"].([]interface{}); len(predictions) != 1 {
+        t.Errorf("after delete: %v", body)
+    }
+}
+
+func TestSyncResultsScoresPredictions(t *testing.T) {
+    app, memory := testApp(t, "user-1")
+    err := memory.SavePredictions([]database.Prediction{
+        {UserID: "exact", MatchID: "A-B", League: "vsl", PredictedScore: "3-1"},
+        {UserID: "winner", MatchID: "A-B", League: "vsl", PredictedScore: "3-0"},
+        {UserID: "wrong", MatchID: "A-B", League: "vsl", PredictedScore: "2-3"},
+        {UserID: "exact", MatchID: "C-D", League: "vsl", PredictedScore: "3-0"},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    results := `{"results":[
+        {"matchId":"A-B","league":"vsl","homeTeam":"A","awayTeam":"B","resultScore":"3-1"},
+        {"matchId":"C-D","league":"vsl","homeTeam":"C","awayTeam":"D","resultScore":"1-0"}]}`
+    if status, _ := request(t, app, "POST", "/api/results/sync", results); status != 401 {
+        t.Errorf("without the cron secret: %d, want 401", status)
+    }
+
+    status, body := request(t, app, "POST", "/api/results/sync", results, "Authorization", "Bearer cron")
+    if status != 200 || body["savedResults"] != 1.0 || body["scoredPredictions"] != 3.0 {
+        t.Fatalf("sync: %d %v", status, body)
+    }
+    if rejected, _ := body["rejected"].([]interface{}); len(rejected) != 1 || !strings.HasPrefix(rejected[0].(string), "C-D: ") {
+        t.Errorf("rejected = %v, want the unfinished C-D", body["rejected"])
+    }
+
+    want := map[string]int{"exact": 15, "winner": 8, "wrong": 0}
+    for user, points := range want {
+        predictions, err := memory.ListPredictions(user, database.PredictionFilter{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        for _, p := range predictions {
+            if p.MatchID != "A-B" { continue }
+            if !p.IsScored || p.PointsEarned != points {
+                t.Errorf("%s: scored %t with %d points, want %d", user, p.IsScored, p.PointsEarned, points)
+            }
+        }
+    }
+    if pending, _ := memory.PendingMatchIDs(); len(pending) != 1 || pending[0] != "C-D" {
+        t.Errorf("pending = %v, want [C-D]", pending)
+    }
+}
+
+func TestCorrectedResultRescoresPredictions(t *testing.T) {
+    app, memory := testApp(t, "user-1")
+    memory.SavePredictions([]database.Prediction{
+        {UserID: "first", MatchID: "A-B", League: "vsl", PredictedScore: "3-1"},
+        {UserID: "second", MatchID: "A-B", League: "vsl", PredictedScore: "3-0"},
+    })
+
+    sync := func(score string) map[string]interface{} {
+        _, body := request(t, app, "POST", "/api/results/sync",
+            `{"results":[{"matchId":"A-B","league":"vsl","homeTeam":"A","awayTeam":"B","resultScore":"`+score+`"}]}`,
+            "Authorization", "Bearer cron")
+        return body
+    }
+    sync("3-1")
+    if body := sync("3-1"); body["scoredPredictions"] != 0.0 {
+        t.Errorf("same result again scored %v predictions, want 0", body["scoredPredictions"])
+    }
+    if body := sync("3-0"); body["scoredPredictions"] != 2.0 {
+        t.Errorf("correction scored %v predictions, want 2", body["scoredPredictions"])
+    }
+
+    for user, points := range map[string]int{"first": 8, "second": 15} {
+        predictions, _ := memory.ListPredictions(user, database.PredictionFilter{})
+        if len(predictions) != 1 || predictions[0].PointsEarned != points {
+            t.Errorf("%s: %v, want %d points", user, predictions, points)
+        }
+    }
+}
+
+func TestScrapedSetsInProgress(t *testing.T) {
+    var logged bytes.Buffer
+    log.SetOutput(&logged)
+    defer log.SetOutput(os.Stderr)
+
+    // A live 2-1 has its sets so far, nothing to drop
+    if sets := scrapedSets("(25-20) (20-25) (25-22)", "2-1"); sets != nil || logged.Len() > 0 {
+        t.Errorf("match in progress: sets %v, logged %q", sets, logged.String())
+    }
+    if sets := scrapedSets("(25-20) (20-25) (25-22) (25-23)", "3-1"); len(sets) != 4 {
+        t.Errorf("finished match: sets %v", sets)
+    }
+    if sets := scrapedSets("(25-20) (20-25)", "3-1"); sets != nil || logged.Len() == 0 {
+        t.Errorf("sets not adding up to a result: %v, logged %q", sets, logged.String())
+    }
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+    "github.com/gofiber/fiber/v2"
+    "go-backend/utils"
+)
+
+// GetScheduleStrength compares the opponents each team has already played
+// with the ones still to come, rated with the current Elo ratings.
+func GetScheduleStrength(c *fiber.Ctx) error {
+    leagueID := c.Params("id")
+
+    teams, matches, err := loadLeague(c, leagueID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "League not found"})
+    }
+
+    window := c.QueryInt("window", 3)
+    if window < 1 {
+        return c.Status(400).JSON(fiber.Map{"error": "window must be at least 1"})
+    }
+
+    // Every team is still needed as an opponent, so a single team is filtered afterwards
+    strengths := utils.CalculateScheduleStrength(teams, matches, utils.EloParamsFor(leagueID), window)
+
+    if teamParam := c.Query("team"); teamParam != "" {
+        team, ok := findTeam(leagueID, teams, teamParam)
+        if !ok {
+            return c.Status(404).JSON(fiber.Map{"error": "Team not found"})
+        }
+        for _, s := range strengths {
+            if s.TeamID == team.ID {
+                return c.JSON(s)
+            }
+        }
+        return c.Status(404).JSON(fiber.Map{"error": "Team not found"})
+    }
+
+    return c.JSON(fiber.Map{
+        "league": leagueID,
+        "window": window,
+        "teams":  strengths,
+    })
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+    "strings"
+
+    "github.com/gofiber/fiber/v2"
+)
+
+// GetStandings recomputes the league table from the played matches and
+// flags the teams whose scraped row disagrees. ?group= limits it to one group.
+func GetStandings(c *fiber.Ctx) error {
+    leagueID := c.Params("id")
+
+    l, ok := findLeague(c, leagueID)
+    if !ok {
+        return c.Status(404).JSON(fiber.Map{"error": "League not found"})
+    }
+
+    groups := l.Standings()
+    if group := c.Query("group"); group != "" {
+        filtered := groups[:0]
+        for _, g := range groups {
+            if strings.EqualFold(g.Group, group) {
+                filtered = append(filtered, g)
+            }
+        }
+        if len(filtered) == 0 {
+            return c.Status(404).JSON(fiber.Map{"error": "Group not found"})
+        }
+        groups = filtered
+    }
+
+    mismatched := 0
+    for _, g := range groups {
+        for _, s := range g.Standings {
+            if len(s.Mismatches) > 0 { mismatched++ }
+        }
+    }
+
+    return c.JSON(fiber.Map{
+        "league":      leagueID,
+        "lastUpdated": l.LastUpdated,
+        "groups":      groups,
+        "mismatched":  mismatched,
+    })
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+    "math"
+    "net/url"
+    "sort"
+
+    "github.com/gofiber/fiber/v2"
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+const teamSimulations = 1000
+
+type Record struct {
+    Played int `json:"played"`
+    Wins   int `json:"wins"`
+    Losses int `json:"losses"`
+}
+
+// TeamCompetition is a team's season in one league
+type TeamCompetition struct {
+    League        string                `json:"league"`
+    LeagueName    string                `json:"leagueName"`
+    TeamID        string                `json:"teamId"`
+    Name          string                `json:"name"`
+    Standing      *league.Standing      `json:"standing"`
+    Home          Record                `json:"home"`
+    Away          Record                `json:"away"`
+    Form          []string              `json:"form"` // newest last
+    SetRatio      float64               `json:"setRatio"`
+    Rating        float64               `json:"rating"`
+    PreviousMatch *league.Fixture       `json:"previousMatch"`
+    NextMatch     *league.Fixture       `json:"nextMatch"`
+    Odds          *utils.SimulationOdds `json:"odds,omitempty"` // league formats with matches left only
+}
+
+// GetTeam aggregates a team across every competition it plays in. The slug
+// is the frontend's team slug or a registry id, see resolveTeamID; a team
+// with a registry id is followed into every league that uses it.
+func GetTeam(c *fiber.Ctx) error {
+    slug := c.Params("slug")
+    if unescaped, err := url.PathUnescape(slug); err == nil {
+        slug = unescaped
+    }
+    formLength := c.QueryInt("form", 5)
+    if formLength < 1 {
+        return c.Status(400).JSON(fiber.Map{"error": "form must be at least 1"})
+    }
+
+    leagues := seasonLeagues(c)
+    configs := league.Leagues.All()
+
+    teamID, candidates := resolveTeamID([]map[string]*league.League{leagues}, configs, slug)
+    if teamID == "" {
+        return teamNotFound(c, candidates)
+    }
+
+    competitions := make([]TeamCompetition, 0)
+    for _, cfg := range configs {
+        l, ok := leagues[cfg.ID]
+        if !ok || mirrors(leagues, cfg) { continue }
+        for _, t := range l.Teams {
+            if t.ID == teamID {
+                competitions = append(competitions, teamCompetition(cfg, l, t, formLength))
+                break
+            }
+        }
+    }
+
+    name := competitions[0].Name
+    if registered, ok := utils.Teams().Lookup(teamID); ok {
+        name = registered.Name
+    }
+
+    return c.JSON(fiber.Map{
+        "slug":         slug,
+        "teamId":       teamID,
+        "name":         name,
+        "competitions": competitions,
+    })
+}
+
+// teamCandidate is one of the teams an ambiguous slug or name matches
+type teamCandidate struct {
+    TeamID string `json:"teamId"`
+    Name   string `json:"name"`
+    League string `json:"league"`
+    tier   int
+}
+
+// resolveTeamID finds the one team a registry id, slug or name refers to in
+// the given seasons. A team id wins; a name several teams share (a club's
+// first team and its reserve sides in the lower leagues) goes to the team
+// of the highest-tier league. Mirror leagues (tvf) only count through the
+// league they copy. The id is "" when nothing matches, or when the best
+// tier still has more than one team, which are returned as candidates.
+func resolveTeamID(seasons []map[string]*league.League, configs []league.Config, param string) (string, []teamCandidate) {
+    slug := utils.Slugify(param)
+    found := make(map[string]*teamCandidate)
+    var order []string
+    for _, leagues := range seasons {
+        for _, cfg := range configs {
+            l, ok := leagues[cfg.ID]
+            if !ok || mirrors(leagues, cfg) { continue }
+            tier := cfg.Tier
+            if tier == 0 { tier = math.MaxInt }
+
+            resolved := utils.ResolveTeam(league.TeamScope(cfg.ID), param)
+            for _, t := range l.Teams {
+                if t.ID == param { return t.ID, nil }
+                if t.ID != resolved && utils.Slugify(t.Name) != slug { continue }
+                if known, ok := found[t.ID]; ok {
+                    if tier < known.tier { known.League, known.tier = cfg.ID, tier }
+                    continue
+                }
+                found[t.ID] = &teamCandidate{TeamID: t.ID, Name: t.Name, League: cfg.ID, tier: tier}
+                order = append(order, t.ID)
+            }
+        }
+    }
+
+    var best []teamCandidate
+    for _, id := range order {
+        candidate := *found[id]
+        if len(best) > 0 && candidate.tier > best[0].tier { continue }
+        if len(best) > 0 && candidate.tier < best[0].tier { best = best[:0] }
+        best = append(best, candidate)
+    }
+    if len(best) == 1 {
+        return best[0].TeamID, nil
+    }
+    return "", best
+}
+
+// teamNotFound answers a slug resolveTeamID could not pin to one team
+func teamNotFound(c *fiber.Ctx, candidates []teamCandidate) error {
+    if len(candidates) > 0 {
+        return c.Status(409).JSON(fiber.Map{"error": "More than one team matches, use a team id", "candidates": candidates})
+    }
+    return c.Status(404).JSON(fiber.Map{"error": "Team not found"})
+}
+
+// mirrors tells whether a league copies another loaded one, as tvf does 2lig
+func mirrors(leagues map[string]*league.League, cfg league.Config) bool {
+    scope := league.TeamScope(cfg.ID)
+    return scope != cfg.ID && leagues[scope] != nil
+}
+
+func teamCompetition(cfg league.Config, l *league.League, team league.Team, formLength int) TeamCompetition {
+    tc := TeamCompetition{
+        League:     cfg.ID,
+        LeagueName: cfg.Name,
+        TeamID:     team.ID,
+        Name:       team.Name,
+        Form:       []string{},
+    }
+
+    for _, g := range l.Standings() {
+        for i := range g.Standings {
+            if g.Standings[i].TeamID == team.ID {
+                tc.Standing = &g.Standings[i]
+                tc.SetRatio = g.Standings[i].SetRatio
+            }
+        }
+    }
+
+    // The team's matches in date order
+    var played, upcoming []league.Fixture
+    for _, f := range l.Fixture {
+        if f.HomeTeamID != team.ID && f.AwayTeamID != team.ID || f.GoldenSet { continue }
+        if f.IsPlayed && f.HomeScore != nil && f.AwayScore != nil {
+            played = append(played, f)
+        } else if !f.IsPlayed {
+            upcoming = append(upcoming, f)
+        }
+    }
+    byDate := func(fs []league.Fixture) {
+        sort.SliceStable(fs, func(i, j int) bool { return fs[i].Date+fs[i].MatchTime < fs[j].Date+fs[j].MatchTime })
+    }
+    byDate(played)
+    byDate(upcoming)
+
+    for _, f := range played {
+        home := f.HomeTeamID == team.ID
+        won := (*f.HomeScore > *f.AwayScore) == home
+        record := &tc.Away
+        if home { record = &tc.Home }
+        record.Played++
+        if won {
+            record.Wins++
+            tc.Form = append(tc.Form, "W")
+        } else {
+            record.Losses++
+            tc.Form = append(tc.Form, "L")
+        }
+    }
+    if len(tc.Form) > formLength { tc.Form = tc.Form[len(tc.Form)-formLength:] }
+    if len(played) > 0 { tc.PreviousMatch = &played[len(played)-1] }
+    if len(upcoming) > 0 { tc.NextMatch = &upcoming[0] }
+
+    teams, matches := l.EloInput()
+    params := utils.EloParamsFor(cfg.ID)
+    tc.Rating = utils.CalculateEloWithParams(teams, matches, params)[team.ID]
+
+    // Knockout formats have no table to finish in
+    if !cfg.HasRounds && len(upcoming) > 0 {
+        groupTeams, groupMatches := groupInput(teams, matches, team.GroupName, l)
+        sim := utils.SimulateSeason(groupTeams, groupMatches, params, teamSimulations)
+        if odds, ok := sim.Odds(team.ID, cfg.PlayoffSpots, cfg.RelegationSpots); ok {
+            tc.Odds = &odds
+        }
+    }
+    return tc
+}
+
+// groupInput narrows the rating input to one group and the matches inside it
+func groupInput(teams []utils.TeamStats, matches []utils.Match, group string, l *league.League) ([]utils.TeamStats, []utils.Match) {
+    inGroup := make(map[string]bool)
+    groupTeams := make([]utils.TeamStats, 0)
+    for i, t := range l.Teams {
+        if t.GroupName == group {
+            inGroup[t.ID] = true
+            groupTeams = append(groupTeams, teams[i])
+        }
+    }
+
+    groupMatches := make([]utils.Match, 0)
+    for _, m := range matches {
+        if inGroup[m.HomeTeamID] && inGroup[m.AwayTeamID] {
+            groupMatches = append(groupMatches, m)
+        }
+    }
+    return groupTeams, groupMatches
+}
@@ -0,0 +1,453 @@
+package league
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    "go-backend/utils"
+)
+
+// Change kinds
+const (
+    ChangeResult        = "result"         // a match got its first result
+    ChangeCorrection    = "correction"     // the result of a played match changed
+    ChangeResultRemoved = "result-removed" // a played match is unplayed again
+    ChangeRescheduled   = "rescheduled"    // date or time of an unplayed match moved
+    ChangePostponed     = "postponed"      // an unplayed match lost its date or was moved after it was due
+    ChangeMatchAdded    = "match-added"
+    ChangeMatchRemoved  = "match-removed"
+    ChangeTeamAdded     = "team-added"
+    ChangeTeamWithdrawn = "team-withdrawn"
+)
+
+// Change is one entry of the data changelog
+type Change struct {
+    ID       int           `json:"id"`
+    At       time.Time     `json:"at"`
+    League   string        `json:"league"`
+    Kind     string        `json:"kind"`
+    Source   string        `json:"source"`            // file for data file updates, or the ingestion path (tvf, sync, admin)
+    MatchID  string        `json:"matchId,omitempty"` // as predictions use it, "home-away"
+    Fixture  string        `json:"fixture,omitempty"` // FixtureKeys key, tells repeated pairings apart
+    HomeTeam string        `json:"homeTeam,omitempty"`
+    AwayTeam string        `json:"awayTeam,omitempty"`
+    Team     string        `json:"team,omitempty"` // team changes
+    Before   *FixtureState `json:"before,omitempty"`
+    After    *FixtureState `json:"after,omitempty"`
+}
+
+// FixtureState is what a change compares of a match
+type FixtureState struct {
+    Date       string `json:"date,omitempty"`
+    MatchTime  string `json:"matchTime,omitempty"`
+    IsPlayed   bool   `json:"isPlayed"`
+    Score      string `json:"score,omitempty"`
+    SetResults string `json:"setResults,omitempty"`
+}
+
+func fixtureState(f Fixture) *FixtureState {
+    return &FixtureState{Date: f.Date, MatchTime: f.MatchTime, IsPlayed: f.IsPlayed, Score: f.Score(), SetResults: f.SetResults}
+}
+
+// MatchID is the id predictions and results use for a fixture
+func (f Fixture) MatchID() string {
+    return f.HomeTeam + "-" + f.AwayTeam
+}
+
+// DiffLeague lists what changed between two versions of a league file.
+// Matches are paired by their teams (and leg), so a match keeps its identity
+// when fixtures are reordered or renumbered.
+func DiffLeague(prev, next *League, now time.Time) []Change {
+    var changes []Change
+    add := func(c Change) {
+        c.League, c.Source = next.ID, "file"
+        changes = append(changes, c)
+    }
+
+    prevTeams := make(map[string]bool, len(prev.Teams))
+    for _, t := range prev.Teams {
+        prevTeams[teamID(t.ID, t.Name)] = true
+    }
+    nextTeams := make(map[string]bool, len(next.Teams))
+    for _, t := range next.Teams {
+        id := teamID(t.ID, t.Name)
+        nextTeams[id] = true
+        if !prevTeams[id] { add(Change{Kind: ChangeTeamAdded, Team: t.Name}) }
+    }
+    for _, t := range prev.Teams {
+        if !nextTeams[teamID(t.ID, t.Name)] { add(Change{Kind: ChangeTeamWithdrawn, Team: t.Name}) }
+    }
+
+    old := fixtureKeys(prev.Fixture)
+    seen := make(map[string]bool, len(old))
+    for key, f := range fixtureKeys(next.Fixture) {
+        c := Change{MatchID: f.MatchID(), Fixture: key, HomeTeam: f.HomeTeam, AwayTeam: f.AwayTeam, After: fixtureState(f)}
+        was, ok := old[key]
+        if !ok {
+            c.Kind = ChangeMatchAdded
+            add(c)
+            continue
+        }
+        seen[key] = true
+        c.Before = fixtureState(was)
+        if c.Kind = fixtureChange(was, f, now); c.Kind != "" { add(c) }
+    }
+    for key, f := range old {
+        if !seen[key] { add(Change{Kind: ChangeMatchRemoved, MatchID: f.MatchID(), Fixture: key, HomeTeam: f.HomeTeam, AwayTeam: f.AwayTeam, Before: fixtureState(f)}) }
+    }
+
+    sortChanges(changes)
+    return changes
+}
+
+// fixtureChange is the kind of change between two versions of a match, ""
+// when nothing we track changed
+func fixtureChange(was, f Fixture, now time.Time) string {
+    switch {
+    case !was.IsPlayed && f.IsPlayed:
+        return ChangeResult
+    case was.IsPlayed && !f.IsPlayed:
+        return ChangeResultRemoved
+    case f.IsPlayed:
+        if was.Score() != f.Score() || was.SetResults != f.SetResults { return ChangeCorrection }
+        return ""
+    case was.Date == f.Date && was.MatchTime == f.MatchTime:
+        return ""
+    }
+
+    today := now.In(utils.TurkeyTime).Format("2006-01-02")
+    if f.Date == "" || (was.Date != "" && was.Date < today && f.Date > was.Date) {
+        return ChangePostponed
+    }
+    return ChangeRescheduled
+}
+
+func teamID(id, name string) string {
+    if id != "" { return id }
+    return utils.TeamKey(name)
+}
+
+// FixtureKeys keys fixtures by teams, leg and golden set, in fixture order.
+// Repeated pairings (playoff series) are numbered in file order.
+func FixtureKeys(fixture []Fixture) []string {
+    keys := make([]string, len(fixture))
+    taken := make(map[string]bool, len(fixture))
+    for i, f := range fixture {
+        base := fmt.Sprintf("%s|%s|%d|%t", teamID(f.HomeTeamID, f.HomeTeam), teamID(f.AwayTeamID, f.AwayTeam), f.Leg, f.GoldenSet)
+        key := base
+        for n := 2; taken[key]; n++ {
+            key = base + "#" + strconv.Itoa(n)
+        }
+        taken[key] = true
+        keys[i] = key
+    }
+    return keys
+}
+
+func fixtureKeys(fixture []Fixture) map[string]Fixture {
+    byKey := make(map[string]Fixture, len(fixture))
+    for i, key := range FixtureKeys(fixture) {
+        byKey[key] = fixture[i]
+    }
+    return byKey
+}
+
+// sortChanges orders changes by match date, team changes first
+func sortChanges(changes []Change) {
+    date := func(c Change) string {
+        if c.After != nil { return c.After.Date }
+        if c.Before != nil { return c.Before.Date }
+        return ""
+    }
+    sort.SliceStable(changes, func(i, j int) bool { return date(changes[i]) < date(changes[j]) })
+}
+
+// changelogSize is how many changes are kept in memory, the file keeps all
+const changelogSize = 5000
+
+// Changelog is the append-only record of data changes, kept as JSON lines
+// next to the league files
+type Changelog struct {
+    mu      sync.Mutex
+    path    string
+    changes []Change          // oldest first
+    nextID  int
+    scores  map[string]string // changeKey -> last recorded score
+    revised map[string]revision
+    trimmed bool // older changes are only in the file
+}
+
+// revision counts the changes of one match, for calendar SEQUENCE numbers
+type revision struct {
+    count int
+    at    time.Time
+}
+
+// OpenChangelog reads an existing changelog, a missing file starts an empty one
+func OpenChangelog(path string) (*Changelog, error) {
+    cl := &Changelog{path: path, nextID: 1, scores: make(map[string]string), revised: make(map[string]revision)}
+    file, err := os.Open(path)
+    if os.IsNotExist(err) {
+        return cl, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for line := 1; scanner.Scan(); line++ {
+        var c Change
+        if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+            return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+        }
+        cl.remember(c)
+    }
+    return cl, scanner.Err()
+}
+
+// changeKey identifies the match of a change in a league, by fixture key
+// when the change has one
+func changeKey(leagueID, fixture, matchID string) string {
+    if fixture != "" { return leagueID + "/" + fixture }
+    return leagueID + "/" + matchID
+}
+
+func (l *Changelog) remember(c Change) {
+    if c.ID >= l.nextID { l.nextID = c.ID + 1 }
+    key := changeKey(c.League, c.Fixture, c.MatchID)
+    if c.MatchID != "" && c.After != nil {
+        l.scores[key] = c.After.Score
+    }
+    if c.MatchID != "" {
+        r := l.revised[key]
+        l.revised[key] = revision{r.count + 1, c.At}
+    }
+    l.changes = append(l.changes, c)
+    if len(l.changes) > changelogSize {
+        l.changes = l.changes[len(l.changes)-changelogSize:]
+        l.trimmed = true
+    }
+}
+
+// Append numbers and stores changes. Results and score corrections already
+// recorded with the same score, e.g. scraped before the data file caught up,
+// are dropped.
+func (l *Changelog) Append(changes []Change) []Change {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    var added []Change
+    for _, c := range changes {
+        if l.recorded(c) { continue }
+        c.ID = l.nextID
+        if c.At.IsZero() { c.At = time.Now().UTC() }
+        l.remember(c)
+        added = append(added, c)
+    }
+    if len(added) > 0 { l.write(added) }
+    return added
+}
+
+// recorded tells a result or correction whose score is the last one
+// recorded for its match. Other kinds, removed matches among them, have no
+// score to compare and are always news.
+func (l *Changelog) recorded(c Change) bool {
+    if (c.Kind != ChangeResult && c.Kind != ChangeCorrection) || c.After == nil {
+        return false
+    }
+    if score, ok := l.scores[changeKey(c.League, c.Fixture, c.MatchID)]; !ok || score != c.After.Score {
+        return false
+    }
+    // set results alone are news
+    return c.Kind == ChangeResult || c.Before == nil || c.Before.Score != c.After.Score
+}
+
+func (l *Changelog) write(changes []Change) {
+    file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if err != nil {
+        log.Printf("Changelog %s: %v", l.path, err)
+        return
+    }
+    defer file.Close()
+    enc := json.NewEncoder(file)
+    for _, c := range changes {
+        if err := enc.Encode(c); err != nil {
+            log.Printf("Changelog %s: %v", l.path, err)
+            return
+        }
+    }
+}
+
+// LastScore is the last result recorded for a match, "" when there is none.
+// The match is its fixture key, or its match id when that is unknown.
+func (l *Changelog) LastScore(leagueID, fixture, matchID string) (string, bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    score, ok := l.scores[changeKey(leagueID, fixture, matchID)]
+    return score, ok
+}
+
+// Latest tells whether the result of c is still the last one recorded for
+// its match
+func (l *Changelog) Latest(c Change) bool {
+    score, ok := l.LastScore(c.League, c.Fixture, c.MatchID)
+    return ok && c.After != nil && score == c.After.Score
+}
+
+// Revision is how many changes were recorded for the match with a fixture
+// key and when the last one was, 0 and the zero time for a match that never
+// changed
+func (l *Changelog) Revision(leagueID, fixture string) (int, time.Time) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    r := l.revised[changeKey(leagueID, fixture, "")]
+    return r.count, r.at
+}
+
+// ChangeFilter selects changes, zero values match everything
+type ChangeFilter struct {
+    AfterID int       // changes with a larger id
+    Since   time.Time // changes recorded at or after
+    League  string
+    Kind    string
+    Limit   int
+}
+
+// ErrChangesGone is returned for changes older than the ones in memory when
+// the changelog file is gone
+var ErrChangesGone = errors.New("older changes are no longer kept")
+
+func (f ChangeFilter) match(c Change) bool {
+    if c.ID <= f.AfterID || c.At.Before(f.Since) { return false }
+    return (f.League == "" || c.League == f.League) && (f.Kind == "" || c.Kind == f.Kind)
+}
+
+// Changes returns the matching changes oldest first, so the last id is the
+// cursor for the next request. Changes older than the ones kept in memory
+// are read from the file.
+func (l *Changelog) Changes(filter ChangeFilter) ([]Change, error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if !l.kept(filter) {
+        return l.readChanges(filter)
+    }
+    changes := make([]Change, 0)
+    for _, c := range l.changes {
+        if !filter.match(c) { continue }
+        changes = append(changes, c)
+        if filter.Limit > 0 && len(changes) == filter.Limit { break }
+    }
+    return changes, nil
+}
+
+// kept tells whether the changes in memory hold all that filter selects
+func (l *Changelog) kept(filter ChangeFilter) bool {
+    if !l.trimmed { return true }
+    oldest := l.changes[0]
+    return (filter.AfterID > 0 && filter.AfterID >= oldest.ID-1) || filter.Since.After(oldest.At)
+}
+
+func (l *Changelog) readChanges(filter ChangeFilter) ([]Change, error) {
+    file, err := os.Open(l.path)
+    if os.IsNotExist(err) {
+        return nil, ErrChangesGone
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    changes := make([]Change, 0)
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for line := 1; scanner.Scan(); line++ {
+        var c Change
+        if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+            return nil, fmt.Errorf("%s:%d: %w", l.path, line, err)
+        }
+        if !filter.match(c) { continue }
+        changes = append(changes, c)
+        if filter.Limit > 0 && len(changes) == filter.Limit { break }
+    }
+    return changes, scanner.Err()
+}
+
+// LastID is the id of the newest change, 0 for an empty changelog
+func (l *Changelog) LastID() int {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return l.nextID - 1
+}
+
+// RecordResult adds a result that came in through an ingestion path rather
+// than a data file. It is a new result or a correction against the last one
+// recorded for the match, or the data file's when the changelog has none.
+// ok is false when the result is already known.
+func (s *Store) RecordResult(source, leagueID, matchID, homeTeam, awayTeam string, result FixtureState) (Change, bool) {
+    score := result.Score
+    if s.changes == nil || score == "" {
+        return Change{}, false
+    }
+
+    var fixture *Fixture
+    var key string
+    if l, ok := s.League(leagueID); ok {
+        if i := resultFixture(l.Fixture, matchID, result.Date); i >= 0 {
+            fixture, key = &l.Fixture[i], FixtureKeys(l.Fixture)[i]
+        }
+    }
+    before, known := s.changes.LastScore(leagueID, key, matchID)
+    if !known && fixture != nil && fixture.IsPlayed { before = fixture.Score() }
+    if before == score {
+        return Change{}, false
+    }
+
+    c := Change{
+        League:   leagueID,
+        Kind:     ChangeResult,
+        Source:   source,
+        MatchID:  matchID,
+        Fixture:  key,
+        HomeTeam: homeTeam,
+        AwayTeam: awayTeam,
+        After:    &result,
+    }
+    c.After.IsPlayed = true
+    if before != "" {
+        c.Kind = ChangeCorrection
+        c.Before = &FixtureState{Date: result.Date, IsPlayed: true, Score: before}
+    }
+    if fixture != nil && c.After.MatchTime == "" { c.After.MatchTime = fixture.MatchTime }
+
+    added := s.changes.Append([]Change{c})
+    if len(added) == 0 {
+        return Change{}, false
+    }
+    return added[0], true
+}
+
+// resultFixture is the index of the match a result of matchID belongs to, -1
+// when there is none. A pairing played more than once takes the match on
+// the result's date, else the first one without a result.
+func resultFixture(fixture []Fixture, matchID, date string) int {
+    found, open := -1, -1
+    for i, f := range fixture {
+        if f.GoldenSet || f.MatchID() != matchID { continue }
+        if date != "" && f.Date == date { return i }
+        if found < 0 { found = i }
+        if open < 0 && !f.IsPlayed { open = i }
+    }
+    if open >= 0 { return open }
+    return found
+}
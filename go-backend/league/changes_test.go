@@ -0,0 +1,136 @@
+package league
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func openTestChangelog(t *testing.T) *Changelog {
+    t.Helper()
+    l, err := OpenChangelog(filepath.Join(t.TempDir(), ChangelogFile))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return l
+}
+
+func result(kind, before, after string) Change {
+    c := Change{League: "vsl", Kind: kind, MatchID: "A-B", HomeTeam: "A", AwayTeam: "B", After: &FixtureState{IsPlayed: true, Score: after}}
+    if before != "" { c.Before = &FixtureState{IsPlayed: true, Score: before} }
+    return c
+}
+
+func TestAppendSkipsRecordedResults(t *testing.T) {
+    l := openTestChangelog(t)
+    l.Append([]Change{result(ChangeResult, "", "3-1")})
+
+    // The data file catching up with a scraped result
+    if added := l.Append([]Change{result(ChangeResult, "", "3-1")}); len(added) != 0 {
+        t.Errorf("same result recorded again: %+v", added)
+    }
+    if added := l.Append([]Change{result(ChangeCorrection, "3-0", "3-1")}); len(added) != 0 {
+        t.Errorf("correction to the recorded score recorded again: %+v", added)
+    }
+    // New set results under the same score are news
+    if added := l.Append([]Change{result(ChangeCorrection, "3-1", "3-1")}); len(added) != 1 {
+        t.Errorf("set correction dropped")
+    }
+    if added := l.Append([]Change{result(ChangeCorrection, "3-1", "3-2")}); len(added) != 1 {
+        t.Errorf("score correction dropped")
+    }
+}
+
+func TestAppendMatchRemoved(t *testing.T) {
+    l := openTestChangelog(t)
+    l.Append([]Change{result(ChangeResult, "", "3-1")})
+
+    // A removed match has no After
+    removed := Change{League: "vsl", Kind: ChangeMatchRemoved, MatchID: "A-B", HomeTeam: "A", AwayTeam: "B", Before: &FixtureState{IsPlayed: true, Score: "3-1"}}
+    if added := l.Append([]Change{removed}); len(added) != 1 {
+        t.Errorf("removed match not recorded: %+v", added)
+    }
+}
+
+func TestRepeatedPairing(t *testing.T) {
+    l := openTestChangelog(t)
+    leg := func(n int, date, score string) Fixture {
+        f := Fixture{HomeTeam: "A", AwayTeam: "B", Date: date, Leg: n}
+        if score != "" {
+            home, away := int(score[0]-'0'), int(score[2]-'0')
+            f.HomeScore, f.AwayScore, f.IsPlayed = &home, &away, true
+        }
+        return f
+    }
+    cup := func(fixture ...Fixture) *League { return &League{ID: "cup", Fixture: fixture} }
+    now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+
+    first := DiffLeague(cup(leg(1, "2026-03-01", ""), leg(2, "2026-03-08", "")), cup(leg(1, "2026-03-01", "3-1"), leg(2, "2026-03-08", "")), now)
+    if added := l.Append(first); len(added) != 1 {
+        t.Fatalf("first leg: %+v", added)
+    }
+    // The second leg ends with the same score and is news all the same
+    second := DiffLeague(cup(leg(1, "2026-03-01", "3-1"), leg(2, "2026-03-08", "")), cup(leg(1, "2026-03-01", "3-1"), leg(2, "2026-03-08", "3-1")), now)
+    if added := l.Append(second); len(added) != 1 || added[0].Fixture == first[0].Fixture {
+        t.Fatalf("second leg: %+v", added)
+    }
+
+    keys := FixtureKeys(cup(leg(1, "", ""), leg(2, "", "")).Fixture)
+    for i, key := range keys {
+        if n, _ := l.Revision("cup", key); n != 1 {
+            t.Errorf("leg %d has %d revisions, want 1", i+1, n)
+        }
+    }
+
+    // A correction from the file, then a newer result ingested for the match
+    correction := Change{League: "cup", Kind: ChangeCorrection, Source: "file", MatchID: "A-B", Fixture: keys[0],
+        Before: &FixtureState{IsPlayed: true, Score: "3-1"}, After: &FixtureState{IsPlayed: true, Score: "3-0"}}
+    correction = l.Append([]Change{correction})[0]
+    if !l.Latest(correction) {
+        t.Error("the correction is not the latest result")
+    }
+    newer := correction
+    newer.Source, newer.Before, newer.After = "admin", correction.After, &FixtureState{IsPlayed: true, Score: "3-2"}
+    l.Append([]Change{newer})
+    if l.Latest(correction) {
+        t.Error("the correction is still the latest result after a newer one")
+    }
+}
+
+func TestChangesBeyondMemory(t *testing.T) {
+    l := openTestChangelog(t)
+    start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+    batch := make([]Change, changelogSize+10)
+    for i := range batch {
+        batch[i] = Change{League: "vsl", Kind: ChangeMatchAdded, At: start.Add(time.Duration(i) * time.Minute)}
+    }
+    l.Append(batch)
+
+    // The first ten are only in the file
+    for _, tc := range []struct {
+        filter ChangeFilter
+        first  int
+    }{
+        {ChangeFilter{AfterID: 3, Limit: 2}, 4},
+        {ChangeFilter{Since: start.Add(3 * time.Minute), Limit: 2}, 4},
+        {ChangeFilter{Limit: 2}, 1},
+    } {
+        changes, err := l.Changes(tc.filter)
+        if err != nil || len(changes) != 2 || changes[0].ID != tc.first {
+            t.Errorf("%+v: %v %+v, want ids from %d", tc.filter, err, changes, tc.first)
+        }
+    }
+    if changes, _ := l.Changes(ChangeFilter{AfterID: 20, Limit: 1}); len(changes) != 1 || changes[0].ID != 21 {
+        t.Errorf("from memory: %+v", changes)
+    }
+
+    os.Remove(l.path)
+    if _, err := l.Changes(ChangeFilter{AfterID: 3}); !errors.Is(err, ErrChangesGone) {
+        t.Errorf("without the file: %v, want ErrChangesGone", err)
+    }
+    if _, err := l.Changes(ChangeFilter{AfterID: 20}); err != nil {
+        t.Errorf("in memory without the file: %v", err)
+    }
+}
@@ -0,0 +1,200 @@
+package league
+
+import (
+    "bytes"
+    "encoding/json"
+    "strings"
+)
+
+// FormatCEVCup is the CEVCupData shape the frontend's /api/cev-cup and
+// /api/cev-challenge routes returned, see Config.APIFormat
+const FormatCEVCup = "cev-cup"
+
+type cevCupTeam struct {
+    Name    string `json:"name"`
+    Country string `json:"country"`
+}
+
+type cevCupMatch struct {
+    ID        int    `json:"id"`
+    Round     string `json:"round"`
+    Leg       int    `json:"leg"`
+    Date      string `json:"date"`
+    HomeTeam  string `json:"homeTeam"`
+    AwayTeam  string `json:"awayTeam"`
+    HomeScore *int   `json:"homeScore"`
+    AwayScore *int   `json:"awayScore"`
+    SetScores string `json:"setScores,omitempty"`
+    IsPlayed  bool   `json:"isPlayed"`
+}
+
+type cevCupData struct {
+    League       string        `json:"league"`
+    Season       string        `json:"season"`
+    CurrentStage string        `json:"currentStage"`
+    Teams        []cevCupTeam  `json:"teams"`
+    Fixture      []cevCupMatch `json:"fixture"`
+}
+
+// CEVCupJSON renders the league as CEVCupData. Files still in the cev-cup
+// and cev-challenge flavours come out byte for byte as the frontend routes
+// served them, files in the typed format are converted.
+func (l *League) CEVCupJSON() ([]byte, error) {
+    switch {
+    case l.flavour == FlavourCEVCup && handMaintained(l.raw):
+        // the route passed the file through JSON.parse and JSON.stringify
+        var buf bytes.Buffer
+        err := json.Compact(&buf, l.raw)
+        return buf.Bytes(), err
+    case l.flavour == FlavourCEVChallenge:
+        return challengeCEVCupJSON(l.raw)
+    }
+
+    data := cevCupData{League: l.Name, Season: l.Season, CurrentStage: l.Stage, Teams: make([]cevCupTeam, 0, len(l.Teams)), Fixture: make([]cevCupMatch, 0, len(l.Fixture))}
+    for _, t := range l.Teams {
+        data.Teams = append(data.Teams, cevCupTeam{Name: t.Name, Country: t.Country})
+    }
+    for _, f := range l.Fixture {
+        if f.GoldenSet { continue }
+        data.Fixture = append(data.Fixture, cevCupMatch{
+            ID:        f.ID,
+            Round:     f.Round,
+            Leg:       f.Leg,
+            Date:      f.Date,
+            HomeTeam:  f.HomeTeam,
+            AwayTeam:  f.AwayTeam,
+            HomeScore: f.HomeScore,
+            AwayScore: f.AwayScore,
+            SetScores: legacySetScores(f.SetResults),
+            IsPlayed:  f.IsPlayed,
+        })
+    }
+    return marshalJS(data)
+}
+
+// handMaintained tells the old cev-cup files from the typed ones the CEV
+// scraper writes, which also have legs
+func handMaintained(content []byte) bool {
+    var file struct {
+        CurrentStage *string `json:"currentStage"`
+    }
+    return json.Unmarshal(content, &file) == nil && file.CurrentStage != nil
+}
+
+// legacySetScores turns "(25-17) (25-17)" back into "(25-17, 25-17)"
+func legacySetScores(sets string) string {
+    sets = strings.TrimSpace(sets)
+    if sets == "" {
+        return ""
+    }
+    parts := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(sets))
+    return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// The challenge route built its response from the phases of the file
+type challengeMatch struct {
+    ID        int             `json:"id"`
+    Round     string          `json:"round"`
+    Leg       int             `json:"leg"`
+    Date      string          `json:"date"`
+    MatchTime string          `json:"matchTime"`
+    HomeTeam  string          `json:"homeTeam"`
+    AwayTeam  string          `json:"awayTeam"`
+    HomeScore *int            `json:"homeScore"`
+    AwayScore *int            `json:"awayScore"`
+    SetScores json.RawMessage `json:"setScores,omitempty"` // as in the file, left out when missing
+    IsPlayed  json.RawMessage `json:"isPlayed,omitempty"`
+    Venue     string          `json:"venue"`
+}
+
+func challengeCEVCupJSON(content []byte) ([]byte, error) {
+    var file struct {
+        Phases []struct {
+            Name    string `json:"name"`
+            Matches []struct {
+                Date     *string         `json:"date"`
+                HomeTeam string          `json:"homeTeam"`
+                AwayTeam string          `json:"awayTeam"`
+                Score    *string         `json:"score"`
+                Sets     json.RawMessage `json:"sets"`
+                IsPlayed json.RawMessage `json:"isPlayed"`
+            } `json:"matches"`
+        } `json:"phases"`
+    }
+    if err := json.Unmarshal(content, &file); err != nil {
+        return nil, err
+    }
+
+    teams := make([]cevCupTeam, 0)
+    seen := make(map[string]bool)
+    addTeam := func(name string) {
+        if !seen[name] {
+            seen[name] = true
+            teams = append(teams, cevCupTeam{Name: name})
+        }
+    }
+
+    fixture := make([]challengeMatch, 0)
+    for _, phase := range file.Phases {
+        for _, m := range phase.Matches {
+            addTeam(m.HomeTeam)
+            addTeam(m.AwayTeam)
+
+            match := challengeMatch{
+                ID:        len(fixture) + 1,
+                Round:     phase.Name,
+                Leg:       1,
+                Date:      "TBD",
+                HomeTeam:  m.HomeTeam,
+                AwayTeam:  m.AwayTeam,
+                SetScores: m.Sets,
+                IsPlayed:  m.IsPlayed,
+            }
+            if m.Date != nil && *m.Date != "" { match.Date = *m.Date }
+            if m.Score != nil && strings.Contains(*m.Score, "-") {
+                parts := strings.Split(*m.Score, "-")
+                match.HomeScore, match.AwayScore = parseJSInt(parts[0]), parseJSInt(parts[1])
+            }
+            fixture = append(fixture, match)
+        }
+    }
+
+    return marshalJS(struct {
+        League       string           `json:"league"`
+        Season       string           `json:"season"`
+        CurrentStage string           `json:"currentStage"`
+        Teams        []cevCupTeam     `json:"teams"`
+        Fixture      []challengeMatch `json:"fixture"`
+    }{"CEV Challenge Cup", "2025-2026", "Main Round", teams, fixture})
+}
+
+// parseJSInt reads the leading integer of s the way JavaScript's parseInt
+// does, nil where it would give NaN
+func parseJSInt(s string) *int {
+    s = strings.TrimSpace(s)
+    sign, digits := 1, 0
+    if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+        if s[0] == '-' { sign = -1 }
+        s = s[1:]
+    }
+    v := 0
+    for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+        v = v*10 + int(s[digits]-'0')
+        digits++
+    }
+    if digits == 0 {
+        return nil
+    }
+    return intPtr(sign * v)
+}
+
+// marshalJS encodes like JSON.stringify, without escaping <, > and &
+func marshalJS(v interface{}) ([]byte, error) {
+    var buf bytes.Buffer
+    enc := json.NewEncoder(&buf)
+    enc.SetEscapeHTML(false)
+    if err := enc.Encode(v); err != nil {
+        return nil, err
+    }
+    return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
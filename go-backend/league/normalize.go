@@ -0,0 +1,314 @@
+package league
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "go-backend/utils"
+)
+
+// Flavour is one of the formats the league data files come in
+type Flavour string
+
+const (
+    // homeScore/awayScore, date as YYYY-MM-DD, lastUpdated (vsl, 1lig, 2lig, cev-cl)
+    FlavourStandard Flavour = "standard"
+    // resultScore, matchDate as DD.MM.YYYY, setRatio, updatedAt (tvf-data.json)
+    FlavourTVF Flavour = "tvf"
+    // round, leg, setScores as "(25-17, 25-17, ...)", currentStage (cev-cup)
+    FlavourCEVCup Flavour = "cev-cup"
+    // phases with score/sets/isGoldenSet matches and no teams block (cev-challenge)
+    FlavourCEVChallenge Flavour = "cev-challenge"
+)
+
+type rawTeam struct {
+    Name      string `json:"name"`
+    GroupName string `json:"groupName"`
+    Country   string `json:"country"`
+    Played    *int   `json:"played"` // nil when the file has no table, only names
+    Wins      int    `json:"wins"`
+    Points    int    `json:"points"`
+    SetsWon   int    `json:"setsWon"`
+    SetsLost  int    `json:"setsLost"`
+}
+
+type rawFixture struct {
+    ID          int    `json:"id"`
+    MatchNo     string `json:"matchNo"`
+    Date        string `json:"date"`
+    MatchDate   string `json:"matchDate"`
+    MatchTime   string `json:"matchTime"`
+    Week        int    `json:"week"`
+    Round       string `json:"round"`
+    GroupName   string `json:"groupName"`
+    HomeTeam    string `json:"homeTeam"`
+    AwayTeam    string `json:"awayTeam"`
+    HomeScore   *int   `json:"homeScore"`
+    AwayScore   *int   `json:"awayScore"`
+    IsPlayed    bool   `json:"isPlayed"`
+    ResultScore string `json:"resultScore"`
+    SetResults  string `json:"setResults"`
+    Venue       string `json:"venue"`
+    City        string `json:"city"`
+    Leg         int    `json:"leg"`
+    SetScores   string `json:"setScores"`
+    Score       string `json:"score"`
+    Sets        string `json:"sets"`
+    IsGoldenSet bool   `json:"isGoldenSet"`
+    GoldenSet   bool   `json:"goldenSet"` // as written from the typed model
+}
+
+type rawPhase struct {
+    Name    string       `json:"name"`
+    Matches []rawFixture `json:"matches"`
+}
+
+type rawFile struct {
+    League      string       `json:"league"`
+    Season      string       `json:"season"`
+    Stage       string       `json:"stage"`
+    CurrentStage string      `json:"currentStage"`
+    Teams       []rawTeam    `json:"teams"`
+    Fixture     []rawFixture `json:"fixture"`
+    LastUpdated string       `json:"lastUpdated"`
+    UpdatedAt   string       `json:"updatedAt"`
+    Phases      []rawPhase   `json:"phases"`
+}
+
+var normalizers = map[Flavour]func(raw *rawFile, l *League){
+    FlavourStandard: normalizeStandard,
+    FlavourTVF:      normalizeTVF,
+    FlavourCEVCup:       normalizeCEVCup,
+    FlavourCEVChallenge: normalizeCEVChallenge,
+}
+
+func detectFlavour(raw *rawFile) Flavour {
+    if len(raw.Phases) > 0 {
+        return FlavourCEVChallenge
+    }
+    for _, f := range raw.Fixture {
+        if f.Leg > 0 || f.SetScores != "" {
+            return FlavourCEVCup
+        }
+    }
+    for _, f := range raw.Fixture {
+        if f.Date != "" || f.HomeScore != nil {
+            return FlavourStandard
+        }
+        if f.MatchDate != "" {
+            return FlavourTVF
+        }
+    }
+    if raw.UpdatedAt != "" && raw.LastUpdated == "" {
+        return FlavourTVF
+    }
+    return FlavourStandard
+}
+
+// NormalizeDate turns the DD.MM.YYYY dates of the TVF files into YYYY-MM-DD
+func NormalizeDate(date string) string {
+    parts := strings.Split(strings.TrimSpace(date), ".")
+    if len(parts) == 3 && len(parts[2]) == 4 {
+        return fmt.Sprintf("%s-%02s-%02s", parts[2], parts[1], parts[0])
+    }
+    return date
+}
+
+func intPtr(v int) *int {
+    return &v
+}
+
+func derefInt(v *int) int {
+    if v == nil { return 0 }
+    return *v
+}
+
+func normalizeFixture(f rawFixture) Fixture {
+    return Fixture{
+        ID:          f.ID,
+        MatchNo:     f.MatchNo,
+        Date:        f.Date,
+        MatchTime:   f.MatchTime,
+        Week:        f.Week,
+        Round:       f.Round,
+        GroupName:   f.GroupName,
+        HomeTeam:    strings.TrimSpace(f.HomeTeam),
+        AwayTeam:    strings.TrimSpace(f.AwayTeam),
+        HomeScore:   f.HomeScore,
+        AwayScore:   f.AwayScore,
+        IsPlayed:    f.IsPlayed,
+        ResultScore: f.ResultScore,
+        SetResults:  f.SetResults,
+        Venue:       f.Venue,
+        City:        f.City,
+        Leg:         f.Leg,
+        GoldenSet:   f.IsGoldenSet || f.GoldenSet,
+    }
+}
+
+func normalizeStandard(raw *rawFile, l *League) {
+    l.LastUpdated = raw.LastUpdated
+    for _, f := range raw.Fixture {
+        l.Fixture = append(l.Fixture, normalizeFixture(f))
+    }
+}
+
+func normalizeTVF(raw *rawFile, l *League) {
+    l.LastUpdated = raw.UpdatedAt
+    for _, f := range raw.Fixture {
+        fixture := normalizeFixture(f)
+        fixture.Date = NormalizeDate(f.MatchDate)
+        l.Fixture = append(l.Fixture, fixture)
+    }
+}
+
+// normalizeSetScores turns "(25-17, 25-17, 22-25)" into the "(25-17) (25-17) (22-25)"
+// form of the other files
+func normalizeSetScores(sets string) string {
+    sets = strings.Trim(strings.TrimSpace(sets), "()")
+    if sets == "" {
+        return ""
+    }
+    parts := strings.Split(sets, ",")
+    for i, p := range parts {
+        parts[i] = "(" + strings.TrimSpace(p) + ")"
+    }
+    return strings.Join(parts, " ")
+}
+
+func normalizeCEVCup(raw *rawFile, l *League) {
+    normalizeStandard(raw, l)
+    if l.Stage == "" {
+        l.Stage = raw.CurrentStage
+    }
+    for i, f := range raw.Fixture {
+        if f.SetResults == "" {
+            l.Fixture[i].SetResults = normalizeSetScores(f.SetScores)
+        }
+    }
+}
+
+func normalizeCEVChallenge(raw *rawFile, l *League) {
+    l.LastUpdated = raw.LastUpdated
+    for _, phase := range raw.Phases {
+        for _, f := range phase.Matches {
+            fixture := normalizeFixture(f)
+            fixture.Round = phase.Name
+            fixture.ResultScore = strings.TrimSpace(f.Score)
+            fixture.SetResults = normalizeSetScores(f.Sets)
+            l.Fixture = append(l.Fixture, fixture)
+        }
+    }
+    l.Teams = DeriveTeams(l.Fixture)
+}
+
+// DeriveTeams builds the teams block of files that have none. Spellings of
+// the same team (with and without a country code) are merged under the
+// shortest one, which the fixture is rewritten to use. Bracket placeholders
+// are left out.
+func DeriveTeams(fixture []Fixture) []Team {
+    names := make(map[string]string)
+    var keys []string
+    for _, f := range fixture {
+        for _, name := range []string{f.HomeTeam, f.AwayTeam} {
+            if name == "" || IsPlaceholder(name) {
+                continue
+            }
+            key := utils.TeamKey(name)
+            old, ok := names[key]
+            if !ok {
+                keys = append(keys, key)
+            }
+            if !ok || len(name) < len(old) {
+                names[key] = name
+            }
+        }
+    }
+
+    for i := range fixture {
+        for _, name := range []*string{&fixture[i].HomeTeam, &fixture[i].AwayTeam} {
+            if canonical, ok := names[utils.TeamKey(*name)]; ok {
+                *name = canonical
+            }
+        }
+    }
+
+    index := make(map[string]int, len(keys))
+    teams := make([]Team, 0, len(keys))
+    for _, key := range keys {
+        index[key] = len(teams)
+        teams = append(teams, Team{Name: names[key]})
+    }
+
+    for _, f := range fixture {
+        h, a, ok := utils.ParseScore(f.ResultScore)
+        if !f.IsPlayed || f.GoldenSet || !ok {
+            continue
+        }
+        hi, hok := index[utils.TeamKey(f.HomeTeam)]
+        ai, aok := index[utils.TeamKey(f.AwayTeam)]
+        if !hok || !aok {
+            continue
+        }
+        hPoints, aPoints := utils.MatchPoints(h, a)
+        home, away := &teams[hi], &teams[ai]
+        home.Played++
+        away.Played++
+        home.Points += hPoints
+        away.Points += aPoints
+        home.SetsWon += h
+        home.SetsLost += a
+        away.SetsWon += a
+        away.SetsLost += h
+        if h > a { home.Wins++ } else { away.Wins++ }
+    }
+    return teams
+}
+
+// Parse decodes a league data file of any flavour into the typed model.
+// Scores are filled in both as homeScore/awayScore and as resultScore.
+func Parse(id string, content []byte) (*League, Flavour, error) {
+    var raw rawFile
+    if err := json.Unmarshal(content, &raw); err != nil {
+        return nil, "", err
+    }
+
+    flavour := detectFlavour(&raw)
+    l := &League{
+        ID:      id,
+        Name:    raw.League,
+        Season:  raw.Season,
+        Stage:   raw.Stage,
+        Teams:   make([]Team, 0, len(raw.Teams)),
+        Fixture: make([]Fixture, 0, len(raw.Fixture)),
+    }
+    for _, t := range raw.Teams {
+        if t.Played != nil { l.scrapedTable = true }
+        l.Teams = append(l.Teams, Team{
+            Name:      strings.TrimSpace(t.Name),
+            GroupName: t.GroupName,
+            Country:   t.Country,
+            Played:    derefInt(t.Played),
+            Wins:      t.Wins,
+            Points:    t.Points,
+            SetsWon:   t.SetsWon,
+            SetsLost:  t.SetsLost,
+        })
+    }
+    normalizers[flavour](&raw, l)
+
+    for i := range l.Fixture {
+        f := &l.Fixture[i]
+        if f.HomeScore == nil && f.AwayScore == nil && f.ResultScore != "" {
+            if h, a, ok := utils.ParseScore(f.ResultScore); ok {
+                f.HomeScore, f.AwayScore = intPtr(h), intPtr(a)
+            }
+        }
+        if f.ResultScore == "" && f.HomeScore != nil && f.AwayScore != nil {
+            f.ResultScore = fmt.Sprintf("%d-%d", *f.HomeScore, *f.AwayScore)
+        }
+    }
+
+    return l, flavour, nil
+}
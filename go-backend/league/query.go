@@ -0,0 +1,138 @@
+package league
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+
+    "go-backend/utils"
+)
+
+// MatchQuery filters a league's fixture. Empty fields match everything.
+type MatchQuery struct {
+    TeamID string
+    Group  string
+    Week   int
+    Round  string
+    From   string // YYYY-MM-DD, inclusive
+    To     string // YYYY-MM-DD, inclusive
+    Played *bool
+    Venue  string // substring, case and diacritic insensitive
+    City   string
+
+    Sort   string // date, week, round or matchNo
+    Desc   bool
+    Cursor string
+    Limit  int
+}
+
+type MatchPage struct {
+    Total      int       `json:"total"`
+    Matches    []Fixture `json:"matches"`
+    NextCursor string    `json:"nextCursor,omitempty"`
+}
+
+var ErrBadCursor = errors.New("invalid cursor")
+
+// Sort keys compare as strings; numbers are zero-padded
+var matchSortKeys = map[string]func(f Fixture) string{
+    "date":    func(f Fixture) string { return f.Date + " " + f.MatchTime },
+    "week":    func(f Fixture) string { return fmt.Sprintf("%06d", f.Week) },
+    "round":   func(f Fixture) string { return f.Round },
+    "matchNo": func(f Fixture) string { return padNumber(f.MatchNo) },
+}
+
+func padNumber(s string) string {
+    if n, err := strconv.Atoi(s); err == nil {
+        return fmt.Sprintf("%010d", n)
+    }
+    return s
+}
+
+func ValidSort(field string) bool {
+    _, ok := matchSortKeys[field]
+    return ok
+}
+
+func containsFold(s, sub string) bool {
+    return strings.Contains(utils.TeamKey(s), utils.TeamKey(sub))
+}
+
+func (q MatchQuery) matches(f Fixture) bool {
+    if q.TeamID != "" && f.HomeTeamID != q.TeamID && f.AwayTeamID != q.TeamID { return false }
+    if q.Group != "" && !strings.EqualFold(f.GroupName, q.Group) { return false }
+    if q.Week != 0 && f.Week != q.Week { return false }
+    if q.Round != "" && !strings.EqualFold(f.Round, q.Round) { return false }
+    if q.From != "" && (f.Date == "" || f.Date < q.From) { return false }
+    if q.To != "" && (f.Date == "" || f.Date > q.To) { return false }
+    if q.Played != nil && f.IsPlayed != *q.Played { return false }
+    if q.Venue != "" && !containsFold(f.Venue, q.Venue) { return false }
+    if q.City != "" && !containsFold(f.City, q.City) { return false }
+    return true
+}
+
+// A cursor is the sort key and fixture index of the last match served, so
+// the next page starts right after it whatever the page size.
+func encodeCursor(key string, index int) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(index) + "|" + key))
+}
+
+func decodeCursor(cursor string) (string, int, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil { return "", 0, ErrBadCursor }
+    index, key, ok := strings.Cut(string(raw), "|")
+    if !ok { return "", 0, ErrBadCursor }
+    i, err := strconv.Atoi(index)
+    if err != nil { return "", 0, ErrBadCursor }
+    return key, i, nil
+}
+
+// Matches returns one page of the filtered, sorted fixture. Ties on the
+// sort key keep file order.
+func (l *League) Matches(q MatchQuery) (MatchPage, error) {
+    keyOf, ok := matchSortKeys[q.Sort]
+    if !ok { keyOf = matchSortKeys["date"] }
+
+    type entry struct {
+        key   string
+        index int
+    }
+    entries := make([]entry, 0)
+    for i, f := range l.Fixture {
+        if q.matches(f) {
+            entries = append(entries, entry{keyOf(f), i})
+        }
+    }
+
+    less := func(a, b entry) bool {
+        if a.key != b.key {
+            return (a.key < b.key) != q.Desc
+        }
+        return a.index < b.index
+    }
+    sort.Slice(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+
+    start := 0
+    if q.Cursor != "" {
+        key, index, err := decodeCursor(q.Cursor)
+        if err != nil { return MatchPage{}, err }
+        after := entry{key, index}
+        start = sort.Search(len(entries), func(i int) bool { return less(after, entries[i]) })
+    }
+
+    end := start + q.Limit
+    if q.Limit <= 0 || end > len(entries) { end = len(entries) }
+
+    page := MatchPage{Total: len(entries), Matches: make([]Fixture, 0, end-start)}
+    for _, e := range entries[start:end] {
+        page.Matches = append(page.Matches, l.Fixture[e.index])
+    }
+    if end < len(entries) {
+        last := entries[end-1]
+        page.NextCursor = encodeCursor(last.key, last.index)
+    }
+    return page, nil
+}
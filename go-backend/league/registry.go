@@ -0,0 +1,158 @@
+package league
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+
+    "go-backend/database"
+)
+
+// Config describes one competition. Adding a competition only needs an
+// entry here (or a row in the leagues table) and its data file.
+type Config struct {
+    ID                    string `json:"id"`
+    Name                  string `json:"name"`
+    ShortName             string `json:"shortName"`
+    Subtitle              string `json:"subtitle,omitempty"`
+    Season                string `json:"season,omitempty"`
+    Country               string `json:"country"`
+    Tier                  int    `json:"tier,omitempty"` // 1 for top flights and European cups, 0 unknown
+    Theme                 string `json:"theme,omitempty"`
+    Icon                  string `json:"icon,omitempty"`
+    File                  string `json:"file"`
+    APIEndpoint           string `json:"apiEndpoint,omitempty"` // legacy route kept for the frontend
+    APIFormat             string `json:"apiFormat,omitempty"`   // shape the legacy route serves, default the typed model
+    TeamScope             string `json:"teamScope,omitempty"`   // league whose team ids these teams share
+    HasGroups             bool   `json:"hasGroups"`
+    HasRounds             bool   `json:"hasRounds"`
+    HasPlayoffs           bool   `json:"hasPlayoffs"`
+    PlayoffSpots          int    `json:"playoffSpots"`
+    SecondaryPlayoffSpots int    `json:"secondaryPlayoffSpots"`
+    RelegationSpots       int    `json:"relegationSpots"`
+    IsActive              bool   `json:"isActive"`
+}
+
+type Registry struct {
+    configs []Config
+    byID    map[string]Config
+}
+
+const DefaultRegistryPath = "config/leagues.json"
+
+// Loaded at startup, read-only afterwards
+var Leagues = NewRegistry(nil)
+
+func NewRegistry(configs []Config) *Registry {
+    r := &Registry{configs: configs, byID: make(map[string]Config, len(configs))}
+    for _, c := range configs {
+        r.byID[c.ID] = c
+    }
+    return r
+}
+
+// LoadRegistry reads the league configs from a JSON file
+func LoadRegistry(path string) error {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+
+    var file struct {
+        Leagues []Config `json:"leagues"`
+    }
+    if err := json.Unmarshal(content, &file); err != nil {
+        return err
+    }
+
+    Leagues = NewRegistry(file.Leagues)
+    return nil
+}
+
+// LoadDatabaseRegistry takes the league list from the leagues table. The
+// table has no data file column, so file, legacy route and format, team
+// scope and tier come from the JSON config when it knows the league, else
+// the file is <id>-data.json.
+func LoadDatabaseRegistry(repo database.LeagueRepository) error {
+    rows, err := repo.ListLeagues()
+    if err != nil {
+        return fmt.Errorf("reading leagues table: %w", err)
+    }
+    if len(rows) == 0 {
+        return fmt.Errorf("leagues table is empty")
+    }
+
+    configs := make([]Config, 0, len(rows))
+    for _, row := range rows {
+        c := Config{
+            ID:                    row.ID,
+            Name:                  row.Name,
+            ShortName:             row.ShortName,
+            Subtitle:              row.Subtitle,
+            Season:                row.Season,
+            Country:               row.Country,
+            Theme:                 row.Theme,
+            Icon:                  row.Icon,
+            File:                  row.ID + "-data.json",
+            HasGroups:             row.HasGroups,
+            HasRounds:             row.HasRounds,
+            HasPlayoffs:           row.HasPlayoffs,
+            PlayoffSpots:          row.PlayoffSpots,
+            SecondaryPlayoffSpots: row.SecondaryPlayoffSpots,
+            RelegationSpots:       row.RelegationSpots,
+            IsActive:              row.IsActive,
+        }
+        if known, ok := Leagues.Get(row.ID); ok {
+            c.File, c.APIEndpoint, c.APIFormat, c.TeamScope = known.File, known.APIEndpoint, known.APIFormat, known.TeamScope
+            c.Tier = known.Tier
+        }
+        configs = append(configs, c)
+    }
+
+    Leagues = NewRegistry(configs)
+    return nil
+}
+
+func (r *Registry) Get(id string) (Config, bool) {
+    c, ok := r.byID[id]
+    return c, ok
+}
+
+// All returns the configs sorted by id
+func (r *Registry) All() []Config {
+    all := make([]Config, len(r.configs))
+    copy(all, r.configs)
+    sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+    return all
+}
+
+// Files maps the active leagues to their data files
+func (r *Registry) Files() map[string]string {
+    files := make(map[string]string)
+    for _, c := range r.configs {
+        if c.IsActive && c.File != "" {
+            files[c.ID] = c.File
+        }
+    }
+    return files
+}
+
+// TeamScope is the league id that team names of a league resolve under
+func TeamScope(id string) string {
+    if c, ok := Leagues.Get(id); ok && c.TeamScope != "" {
+        return c.TeamScope
+    }
+    return id
+}
+
+// IsPlaceholder reports bracket slots such as "Winner CHCW 93/94"
+func IsPlaceholder(name string) bool {
+    for _, prefix := range []string{"Winner ", "Loser ", "TBD"} {
+        if strings.HasPrefix(name, prefix) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,152 @@
+package league
+
+import (
+    "sort"
+
+    "go-backend/utils"
+)
+
+// Standing is one row of a table computed from the played matches
+type Standing struct {
+    Rank       int      `json:"rank"`
+    TeamID     string   `json:"teamId"`
+    Name       string   `json:"name"`
+    GroupName  string   `json:"groupName,omitempty"`
+    Played     int      `json:"played"`
+    Wins       int      `json:"wins"`
+    Losses     int      `json:"losses"`
+    Points     int      `json:"points"`
+    SetsWon    int      `json:"setsWon"`
+    SetsLost   int      `json:"setsLost"`
+    SetRatio   float64  `json:"setRatio"`
+    PointsWon  int      `json:"pointsWon"` // rally points, only from matches with set scores
+    PointsLost int      `json:"pointsLost"`
+    PointRatio float64  `json:"pointRatio"`
+    Form       []string `json:"form"` // last 5, newest last: "W" or "L"
+
+    // Fields where the scraped teams block disagrees with the results,
+    // left out for files without a table
+    Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+type Mismatch struct {
+    Field    string `json:"field"`
+    Computed int    `json:"computed"`
+    Scraped  int    `json:"scraped"`
+}
+
+type StandingsGroup struct {
+    Group     string     `json:"group"`
+    Standings []Standing `json:"standings"`
+}
+
+// Standings recomputes the table of every group from the played matches.
+// Order: utils.CompareStandings, then points taken in the matches between
+// the two teams.
+func (l *League) Standings() []StandingsGroup {
+    rows := make(map[string]*Standing, len(l.Teams))
+    order := make([]string, 0, len(l.Teams))
+    for _, t := range l.Teams {
+        if _, ok := rows[t.ID]; ok { continue }
+        rows[t.ID] = &Standing{TeamID: t.ID, Name: t.Name, GroupName: t.GroupName, Form: []string{}}
+        order = append(order, t.ID)
+    }
+
+    // head to head points, keyed by team then opponent
+    h2h := make(map[string]map[string]int)
+    addH2H := func(team, opponent string, points int) {
+        if h2h[team] == nil { h2h[team] = make(map[string]int) }
+        h2h[team][opponent] += points
+    }
+
+    played := make([]Fixture, 0, len(l.Fixture))
+    for _, f := range l.Fixture {
+        if f.IsPlayed && !f.GoldenSet && f.HomeScore != nil && f.AwayScore != nil {
+            played = append(played, f)
+        }
+    }
+    sort.SliceStable(played, func(i, j int) bool { return played[i].Date < played[j].Date })
+
+    for _, f := range played {
+        home, away := rows[f.HomeTeamID], rows[f.AwayTeamID]
+        if home == nil || away == nil { continue }
+
+        h, a := *f.HomeScore, *f.AwayScore
+        hPoints, aPoints := utils.MatchPoints(h, a)
+        home.Played++
+        away.Played++
+        home.Points += hPoints
+        away.Points += aPoints
+        home.SetsWon += h
+        home.SetsLost += a
+        away.SetsWon += a
+        away.SetsLost += h
+        addH2H(f.HomeTeamID, f.AwayTeamID, hPoints)
+        addH2H(f.AwayTeamID, f.HomeTeamID, aPoints)
+
+        for _, s := range utils.ParseSets(f.SetResults) {
+            home.PointsWon += s.Home
+            home.PointsLost += s.Away
+            away.PointsWon += s.Away
+            away.PointsLost += s.Home
+        }
+
+        winner, loser := home, away
+        if a > h { winner, loser = away, home }
+        winner.Wins++
+        loser.Losses++
+        winner.Form = append(winner.Form, "W")
+        loser.Form = append(loser.Form, "L")
+    }
+
+    for _, t := range l.Teams {
+        row := rows[t.ID]
+        if len(row.Form) > 5 { row.Form = row.Form[len(row.Form)-5:] }
+        row.SetRatio = utils.Ratio(row.SetsWon, row.SetsLost)
+        row.PointRatio = utils.Ratio(row.PointsWon, row.PointsLost)
+        if l.scrapedTable { row.Mismatches = compareScraped(row, t) }
+    }
+
+    groups := make(map[string][]Standing)
+    var names []string
+    for _, id := range order {
+        row := rows[id]
+        if _, ok := groups[row.GroupName]; !ok { names = append(names, row.GroupName) }
+        groups[row.GroupName] = append(groups[row.GroupName], *row)
+    }
+    sort.Strings(names)
+
+    result := make([]StandingsGroup, 0, len(names))
+    for _, name := range names {
+        table := groups[name]
+        sort.SliceStable(table, func(i, j int) bool {
+            a, b := table[i], table[j]
+            if order := utils.CompareStandings(a.order(), b.order()); order != 0 { return order < 0 }
+            return h2h[a.TeamID][b.TeamID] > h2h[b.TeamID][a.TeamID]
+        })
+        for i := range table {
+            table[i].Rank = i + 1
+        }
+        result = append(result, StandingsGroup{Group: name, Standings: table})
+    }
+    return result
+}
+
+func (s Standing) order() utils.TableOrder {
+    return utils.TableOrder{Wins: s.Wins, Points: s.Points, SetRatio: s.SetRatio, PointRatio: s.PointRatio}
+}
+
+func compareScraped(row *Standing, t Team) []Mismatch {
+    var mismatches []Mismatch
+    check := func(field string, computed, scraped int) {
+        if computed != scraped {
+            mismatches = append(mismatches, Mismatch{field, computed, scraped})
+        }
+    }
+    check("played", row.Played, t.Played)
+    check("wins", row.Wins, t.Wins)
+    check("points", row.Points, t.Points)
+    check("setsWon", row.SetsWon, t.SetsWon)
+    check("setsLost", row.SetsLost, t.SetsLost)
+    return mismatches
+}
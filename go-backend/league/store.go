@@ -0,0 +1,354 @@
+package league
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "sync/atomic"
+    "time"
+)
+
+// Snapshot is an immutable view of every league file. Handlers must not
+// modify anything reachable from it.
+type Snapshot struct {
+    Leagues  map[string]*League // current files, directly in the data directory
+    Files    map[string]FileInfo
+    LoadedAt time.Time
+    Version  string    // changes whenever a league or a file error changes
+    Modified time.Time // newest league file
+
+    // Past seasons from data/<season>/, by season then league id
+    Archive      map[string]map[string]*League
+    ArchiveFiles map[string]map[string]FileInfo
+}
+
+type FileInfo struct {
+    File    string    `json:"file"`
+    ModTime time.Time `json:"modTime"`
+    Size    int64     `json:"size"`
+    Error   string    `json:"error,omitempty"`
+    Report  *Report   `json:"validation,omitempty"`
+}
+
+// Store keeps the league files in memory and swaps in a new snapshot when
+// a file on disk changes.
+type Store struct {
+    dir     string
+    files   map[string]string // league id -> file name
+    current atomic.Pointer[Snapshot]
+    changes *Changelog // nil when reloads are not recorded
+}
+
+var Data *Store
+
+// ChangelogFile is the changelog's file in the data directory
+const ChangelogFile = "changelog.jsonl"
+
+// Init loads the files of the registered leagues from dir and starts
+// watching them for changes, recording what each reload changed
+func Init(dir string) error {
+    Data = NewStore(dir, Leagues.Files())
+    changes, err := OpenChangelog(filepath.Join(dir, ChangelogFile))
+    if err != nil {
+        return err
+    }
+    Data.changes = changes
+    if err := Data.Load(); err != nil {
+        return err
+    }
+    go Data.Watch(context.Background(), 2*time.Second)
+    return nil
+}
+
+func NewStore(dir string, files map[string]string) *Store {
+    s := &Store{dir: dir, files: files}
+    s.current.Store(&Snapshot{Leagues: map[string]*League{}, Files: map[string]FileInfo{}})
+    return s
+}
+
+func (s *Store) Snapshot() *Snapshot {
+    return s.current.Load()
+}
+
+// Changes is the store's changelog, nil for stores not started by Init
+func (s *Store) Changes() *Changelog {
+    return s.changes
+}
+
+// Path is the data file of a league, where scrapers write it
+func (s *Store) Path(id string) (string, bool) {
+    file, ok := s.files[id]
+    if !ok { return "", false }
+    return filepath.Join(s.dir, file), true
+}
+
+func (s *Store) League(id string) (*League, bool) {
+    l, ok := s.Snapshot().Leagues[id]
+    return l, ok
+}
+
+// Archived seasons live in subdirectories named like "2024-2025"
+var seasonDir = regexp.MustCompile(`^\d{4}-\d{4}$`)
+
+// seasons lists the archive directories of the data directory
+func (s *Store) seasons() []string {
+    entries, err := os.ReadDir(s.dir)
+    if err != nil {
+        return nil
+    }
+    var seasons []string
+    for _, e := range entries {
+        if e.IsDir() && seasonDir.MatchString(e.Name()) {
+            seasons = append(seasons, e.Name())
+        }
+    }
+    return seasons
+}
+
+// loadDir loads the league files of one directory into leagues and files.
+// Failed files keep their version from prev. With optional set, missing
+// files are skipped instead of reported. Returns the number loaded.
+func (s *Store) loadDir(dir string, optional bool, prev, leagues map[string]*League, files map[string]FileInfo) int {
+    loaded := 0
+    for id, file := range s.files {
+        path := filepath.Join(dir, file)
+        info := FileInfo{File: file}
+
+        stat, err := os.Stat(path)
+        if err != nil && optional {
+            continue
+        }
+        if err == nil {
+            info.ModTime, info.Size = stat.ModTime(), stat.Size()
+            var l *League
+            if l, info.Report, err = LoadFile(path, id); err == nil {
+                l.modified = info.ModTime
+                leagues[id] = l
+                loaded++
+            }
+        }
+
+        if err != nil {
+            info.Error = err.Error()
+            if old, ok := prev[id]; ok {
+                leagues[id] = old
+            }
+            log.Printf("League %s: %v", path, err)
+        }
+        files[id] = info
+    }
+    return loaded
+}
+
+// Load reads every league file and swaps in the new snapshot. A file that
+// fails to load or validate keeps its previous version, so a half-written or
+// broken file never takes a league offline.
+func (s *Store) Load() error {
+    prev := s.Snapshot()
+    next := &Snapshot{
+        Leagues:      make(map[string]*League, len(s.files)),
+        Files:        make(map[string]FileInfo, len(s.files)),
+        LoadedAt:     time.Now().UTC(),
+        Archive:      make(map[string]map[string]*League),
+        ArchiveFiles: make(map[string]map[string]FileInfo),
+    }
+
+    loaded := s.loadDir(s.dir, false, prev.Leagues, next.Leagues, next.Files)
+
+    for _, season := range s.seasons() {
+        leagues, files := make(map[string]*League), make(map[string]FileInfo)
+        s.loadDir(filepath.Join(s.dir, season), true, prev.Archive[season], leagues, files)
+        if len(files) > 0 {
+            next.Archive[season], next.ArchiveFiles[season] = leagues, files
+        }
+    }
+
+    next.Version, next.Modified = snapshotVersion(next)
+    s.current.Store(next)
+    s.recordChanges(prev, next)
+    if loaded == 0 && len(s.files) > 0 {
+        return fmt.Errorf("no league data could be loaded from %s", s.dir)
+    }
+    return nil
+}
+
+// recordChanges adds what changed in the current files to the changelog.
+// Leagues without a previous version, as on startup, have nothing to diff.
+func (s *Store) recordChanges(prev, next *Snapshot) {
+    if s.changes == nil { return }
+    ids := make([]string, 0, len(next.Leagues))
+    for id := range next.Leagues {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    now := time.Now()
+    var changes []Change
+    for _, id := range ids {
+        if old, ok := prev.Leagues[id]; ok && old.version != next.Leagues[id].version {
+            changes = append(changes, DiffLeague(old, next.Leagues[id], now)...)
+        }
+    }
+    if added := s.changes.Append(changes); len(added) > 0 {
+        log.Printf("League data changelog: %d changes", len(added))
+    }
+}
+
+func snapshotVersion(snap *Snapshot) (string, time.Time) {
+    var modified time.Time
+    h := sha256.New()
+
+    add := func(season string, leagues map[string]*League, files map[string]FileInfo) {
+        ids := make([]string, 0, len(files))
+        for id := range files {
+            ids = append(ids, id)
+        }
+        sort.Strings(ids)
+        for _, id := range ids {
+            version := ""
+            if l, ok := leagues[id]; ok {
+                version = l.version
+                if l.modified.After(modified) { modified = l.modified }
+            }
+            fmt.Fprintf(h, "%s/%s=%s;%s\n", season, id, version, files[id].Error)
+        }
+    }
+
+    add("", snap.Leagues, snap.Files)
+    for _, season := range snap.seasonNames() {
+        add(season, snap.Archive[season], snap.ArchiveFiles[season])
+    }
+    return hex.EncodeToString(h.Sum(nil)[:8]), modified
+}
+
+func (snap *Snapshot) seasonNames() []string {
+    seasons := make([]string, 0, len(snap.Archive))
+    for season := range snap.Archive {
+        seasons = append(seasons, season)
+    }
+    sort.Strings(seasons)
+    return seasons
+}
+
+// Season returns the leagues of one season by id. "" is the current files;
+// a league whose current file is tagged with the season is taken from there,
+// the others from the archive.
+func (snap *Snapshot) Season(season string) map[string]*League {
+    if season == "" {
+        return snap.Leagues
+    }
+    leagues := make(map[string]*League)
+    for id, l := range snap.Archive[season] {
+        leagues[id] = l
+    }
+    for id, l := range snap.Leagues {
+        if l.Season == season {
+            leagues[id] = l
+        }
+    }
+    return leagues
+}
+
+// Seasons lists the seasons a league has data for, newest first
+func (snap *Snapshot) Seasons(id string) []string {
+    seen := make(map[string]bool)
+    if l, ok := snap.Leagues[id]; ok && l.Season != "" {
+        seen[l.Season] = true
+    }
+    for season, leagues := range snap.Archive {
+        if _, ok := leagues[id]; ok {
+            seen[season] = true
+        }
+    }
+
+    seasons := make([]string, 0, len(seen))
+    for season := range seen {
+        seasons = append(seasons, season)
+    }
+    sort.Sort(sort.Reverse(sort.StringSlice(seasons)))
+    return seasons
+}
+
+// LeagueIn returns a league of the given season, "" for the current one
+func (s *Store) LeagueIn(id, season string) (*League, bool) {
+    l, ok := s.Snapshot().Season(season)[id]
+    return l, ok
+}
+
+// Version returns the version and modification time of one league of a
+// season, or of the whole snapshot when id is empty. ok is false for
+// unknown leagues.
+func (s *Store) Version(id, season string) (string, time.Time, bool) {
+    snap := s.Snapshot()
+    if id == "" {
+        return snap.Version, snap.Modified, true
+    }
+    l, ok := snap.Season(season)[id]
+    if !ok {
+        return "", time.Time{}, false
+    }
+    return l.version, l.modified, true
+}
+
+// changed reports whether any league file differs from the current snapshot
+func (s *Store) changed() bool {
+    snap := s.Snapshot()
+
+    differs := func(dir string, optional bool, files map[string]FileInfo) bool {
+        for id, file := range s.files {
+            stat, err := os.Stat(filepath.Join(dir, file))
+            old, known := files[id]
+            if err != nil {
+                if optional && !known { continue }
+                if optional || old.Error == "" { return true }
+                continue
+            }
+            if !known || !stat.ModTime().Equal(old.ModTime) || stat.Size() != old.Size {
+                return true
+            }
+        }
+        return false
+    }
+
+    if differs(s.dir, false, snap.Files) {
+        return true
+    }
+
+    seasons := s.seasons()
+    archived := 0
+    for _, season := range seasons {
+        if differs(filepath.Join(s.dir, season), true, snap.ArchiveFiles[season]) {
+            return true
+        }
+        if len(snap.ArchiveFiles[season]) > 0 { archived++ }
+    }
+    // a season directory was removed
+    return archived != len(snap.ArchiveFiles)
+}
+
+// Watch polls the data directory and reloads when a file changes
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if s.changed() {
+                if err := s.Load(); err != nil {
+                    log.Printf("League data reload failed: %v", err)
+                } else {
+                    log.Printf("League data reloaded")
+                }
+            }
+        }
+    }
+}
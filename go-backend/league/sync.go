@@ -0,0 +1,439 @@
+package league
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "go-backend/database"
+    "go-backend/utils"
+)
+
+const (
+    SyncInsert = "insert"
+    SyncUpdate = "update"
+    SyncDelete = "delete"
+)
+
+// SyncChange is one row that differs between a league file and the database
+type SyncChange struct {
+    Table  string   `json:"table"`
+    Action string   `json:"action"`
+    Key    string   `json:"key"`
+    Fields []string `json:"fields,omitempty"` // changed columns of an update
+
+    id  string                 // database id of updated and deleted rows
+    row map[string]interface{} // columns to write
+}
+
+// SyncPlan lists the changes of one league in the order they are written
+type SyncPlan struct {
+    League  string       `json:"league"`
+    Changes []SyncChange `json:"changes"`
+}
+
+func (p *SyncPlan) Count(action string) int {
+    n := 0
+    for _, ch := range p.Changes {
+        if ch.Action == action { n++ }
+    }
+    return n
+}
+
+type syncTable struct {
+    name     string
+    existing map[string]map[string]interface{} // by key
+    desired  map[string]map[string]interface{}
+    order    []string // desired keys in file order
+}
+
+func newSyncTable(name string) *syncTable {
+    return &syncTable{
+        name:     name,
+        existing: make(map[string]map[string]interface{}),
+        desired:  make(map[string]map[string]interface{}),
+    }
+}
+
+// want adds a row the table should hold. Two rows with one key would
+// overwrite each other, so the second one is an error.
+func (t *syncTable) want(key string, row map[string]interface{}) error {
+    if _, ok := t.desired[key]; ok {
+        return fmt.Errorf("%s: two rows for %s", t.name, key)
+    }
+    t.desired[key] = row
+    t.order = append(t.order, key)
+    return nil
+}
+
+// changes diffs the desired rows against the existing ones. Existing rows
+// sharing a key are duplicates and all but one are deleted.
+func (t *syncTable) changes(existing []map[string]interface{}, keyOf func(map[string]interface{}) string) []SyncChange {
+    var changes []SyncChange
+    var stale []SyncChange
+    for _, row := range existing {
+        key := keyOf(row)
+        if _, dup := t.existing[key]; dup {
+            stale = append(stale, SyncChange{Table: t.name, Action: SyncDelete, Key: key + " (duplicate)", id: rowID(row)})
+            continue
+        }
+        t.existing[key] = row
+    }
+
+    for _, key := range t.order {
+        want := t.desired[key]
+        have, ok := t.existing[key]
+        if !ok {
+            changes = append(changes, SyncChange{Table: t.name, Action: SyncInsert, Key: key, row: want})
+            continue
+        }
+
+        update := SyncChange{Table: t.name, Action: SyncUpdate, Key: key, id: rowID(have), row: make(map[string]interface{})}
+        for column, value := range want {
+            if !sameValue(value, have[column]) {
+                update.Fields = append(update.Fields, column)
+                update.row[column] = value
+            }
+        }
+        if len(update.Fields) > 0 {
+            sort.Strings(update.Fields)
+            changes = append(changes, update)
+        }
+    }
+
+    keys := make([]string, 0)
+    for key := range t.existing {
+        if _, ok := t.desired[key]; !ok { keys = append(keys, key) }
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        stale = append(stale, SyncChange{Table: t.name, Action: SyncDelete, Key: key, id: rowID(t.existing[key])})
+    }
+    return append(changes, stale...)
+}
+
+func rowID(row map[string]interface{}) string {
+    id, _ := row["id"].(string)
+    return id
+}
+
+// sameValue compares a value to write with one read back from PostgREST,
+// which returns timestamps with an offset and numbers as JSON numbers
+func sameValue(want, have interface{}) bool {
+    a, _ := json.Marshal(want)
+    b, _ := json.Marshal(have)
+    if string(a) == string(b) { return true }
+
+    ws, ok1 := want.(string)
+    hs, ok2 := have.(string)
+    if ok1 && ok2 {
+        wt, err1 := time.Parse(time.RFC3339, ws)
+        ht, err2 := time.Parse(time.RFC3339, hs)
+        return err1 == nil && err2 == nil && wt.Equal(ht)
+    }
+
+    wf, ok1 := want.(float64)
+    hf, ok2 := have.(float64)
+    return ok1 && ok2 && math.Abs(wf-hf) < 1e-9
+}
+
+// nullable stores empty strings and zero numbers as NULL
+func nullable(v interface{}) interface{} {
+    switch x := v.(type) {
+    case string:
+        if x == "" { return nil }
+    case int:
+        if x == 0 { return nil }
+    }
+    return v
+}
+
+// numeric rounds a ratio to the NUMERIC(5,3) columns of standings
+func numeric(f float64) float64 {
+    return math.Min(math.Round(f*1000)/1000, 99.999)
+}
+
+func applyChanges(repo database.LeagueRepository, table string, changes []SyncChange) error {
+    var inserts []map[string]interface{}
+    var deletes []string
+    for _, ch := range changes {
+        switch ch.Action {
+        case SyncInsert:
+            inserts = append(inserts, ch.row)
+        case SyncUpdate:
+            if err := repo.UpdateRow(table, ch.id, ch.row); err != nil {
+                return fmt.Errorf("updating %s %s: %w", table, ch.Key, err)
+            }
+        case SyncDelete:
+            deletes = append(deletes, ch.id)
+        }
+    }
+
+    if len(inserts) > 0 {
+        if err := repo.InsertRows(table, inserts); err != nil {
+            return fmt.Errorf("inserting into %s: %w", table, err)
+        }
+    }
+    if len(deletes) > 0 {
+        if err := repo.DeleteRows(table, deletes); err != nil {
+            return fmt.Errorf("deleting from %s: %w", table, err)
+        }
+    }
+    return nil
+}
+
+func selectRows(repo database.LeagueRepository, table, column, value string) ([]map[string]interface{}, error) {
+    rows, err := repo.Rows(table, column, value)
+    if err != nil {
+        return nil, fmt.Errorf("reading %s: %w", table, err)
+    }
+    return rows, nil
+}
+
+// Sync writes a league into the normalized tables of the leagues migration:
+// leagues, teams, matches and standings. Rows are compared with what the
+// database holds and only differences are written, so a second run changes
+// nothing. Matches have no key in the schema and are matched on the teams,
+// group, week, round and leg, and on the day when a pairing still repeats.
+// A file with two such matches on one day is an error. Golden sets have no
+// column and are left out, as is
+// playoff_matches since the files carry no playoff series.
+//
+// The writes of a league go in one transaction, so a failed run leaves the
+// tables as they were. With dryRun nothing is written; rows of teams the run
+// would insert then compare without their team uuid.
+func Sync(repo database.LeagueRepository, cfg Config, l *League, dryRun bool) (*SyncPlan, error) {
+    if dryRun {
+        plan := &SyncPlan{League: cfg.ID}
+        return plan, syncLeague(repo, cfg, l, plan, true)
+    }
+
+    plan := &SyncPlan{League: cfg.ID}
+    err := repo.InTx(func(tx database.LeagueRepository) error {
+        return syncLeague(tx, cfg, l, plan, false)
+    })
+    return plan, err
+}
+
+func syncLeague(repo database.LeagueRepository, cfg Config, l *League, plan *SyncPlan, dryRun bool) error {
+    step := func(table string, changes []SyncChange) error {
+        plan.Changes = append(plan.Changes, changes...)
+        if dryRun { return nil }
+        return applyChanges(repo, table, changes)
+    }
+
+    // League row, needed by the foreign keys of the other tables
+    leagues := newSyncTable("leagues")
+    if err := leagues.want(cfg.ID, leagueRecord(cfg)); err != nil { return err }
+    existing, err := selectRows(repo, "leagues", "id", cfg.ID)
+    if err != nil { return err }
+    if err := step("leagues", leagues.changes(existing, rowID)); err != nil { return err }
+
+    // Teams, keyed by slug (the stable team id)
+    teams := newSyncTable("teams")
+    for _, t := range l.Teams {
+        err := teams.want(t.ID, map[string]interface{}{
+            "league_id":  cfg.ID,
+            "name":       t.Name,
+            "slug":       t.ID,
+            "group_name": nullable(t.GroupName),
+        })
+        if err != nil { return err }
+    }
+    existing, err = selectRows(repo, "teams", "league_id", cfg.ID)
+    if err != nil { return err }
+    slugOf := func(row map[string]interface{}) string { s, _ := row["slug"].(string); return s }
+    if err := step("teams", teams.changes(existing, slugOf)); err != nil { return err }
+
+    // Team uuids, read back after the writes
+    if !dryRun {
+        if existing, err = selectRows(repo, "teams", "league_id", cfg.ID); err != nil { return err }
+    }
+    uuidOf := make(map[string]interface{})
+    slugByUUID := make(map[string]string)
+    for _, row := range existing {
+        slug := slugOf(row)
+        if _, ok := teams.desired[slug]; !ok { continue }
+        uuidOf[slug] = row["id"]
+        slugByUUID[rowID(row)] = slug
+    }
+
+    // Matches: a team is referred to by slug when the league lists it, else
+    // by name (placeholders such as "Winner of ...")
+    teamKey := func(id, name string) string {
+        if _, ok := teams.desired[id]; ok { return id }
+        return name
+    }
+    matches := newSyncTable("matches")
+    var fixtures []Fixture
+    var bases, days []string
+    for _, f := range l.Fixture {
+        if f.GoldenSet { continue }
+        fixtures = append(fixtures, f)
+        bases = append(bases, matchKey(f.GroupName, f.Week, f.Round, f.Leg, teamKey(f.HomeTeamID, f.HomeTeam), teamKey(f.AwayTeamID, f.AwayTeam)))
+        days = append(days, matchDay(matchDate(f)))
+    }
+    for i, key := range matchKeys(bases, days) {
+        if err := matches.want(key, matchRecord(cfg.ID, fixtures[i], uuidOf)); err != nil { return err }
+    }
+    existing, err = selectRows(repo, "matches", "league_id", cfg.ID)
+    if err != nil { return err }
+    bases, days = bases[:0], days[:0]
+    for _, row := range existing {
+        side := func(prefix string) string {
+            id, _ := row[prefix+"_team_id"].(string)
+            name, _ := row[prefix+"_team_name"].(string)
+            if slug, ok := slugByUUID[id]; ok { return slug }
+            return name
+        }
+        group, _ := row["group_name"].(string)
+        round, _ := row["round"].(string)
+        week, _ := row["week"].(float64)
+        leg, _ := row["leg"].(float64)
+        bases = append(bases, matchKey(group, int(week), round, int(leg), side("home"), side("away")))
+        days = append(days, matchDay(row["match_date"]))
+    }
+    existingKeys := make(map[string]string, len(existing))
+    for i, key := range matchKeys(bases, days) {
+        existingKeys[rowID(existing[i])] = key
+    }
+    matchKeyOf := func(row map[string]interface{}) string { return existingKeys[rowID(row)] }
+    if err := step("matches", matches.changes(existing, matchKeyOf)); err != nil { return err }
+
+    // Standings computed from the results, one row per team
+    standings := newSyncTable("standings")
+    for _, g := range l.Standings() {
+        for _, s := range g.Standings {
+            if err := standings.want(s.TeamID, standingRecord(cfg.ID, s, uuidOf[s.TeamID])); err != nil { return err }
+        }
+    }
+    existing, err = selectRows(repo, "standings", "league_id", cfg.ID)
+    if err != nil { return err }
+    standingKeyOf := func(row map[string]interface{}) string {
+        id, _ := row["team_id"].(string)
+        return slugByUUID[id]
+    }
+    if err := step("standings", standings.changes(existing, standingKeyOf)); err != nil { return err }
+
+    return nil
+}
+
+func leagueRecord(cfg Config) map[string]interface{} {
+    row := map[string]interface{}{
+        "id":                      cfg.ID,
+        "name":                    cfg.Name,
+        "short_name":              cfg.ShortName,
+        "subtitle":                nullable(cfg.Subtitle),
+        "has_groups":              cfg.HasGroups,
+        "has_rounds":              cfg.HasRounds,
+        "has_playoffs":            cfg.HasPlayoffs,
+        "playoff_spots":           cfg.PlayoffSpots,
+        "secondary_playoff_spots": cfg.SecondaryPlayoffSpots,
+        "relegation_spots":        cfg.RelegationSpots,
+        "is_active":               cfg.IsActive,
+    }
+    if cfg.ShortName == "" { row["short_name"] = cfg.Name }
+    // Left to the column defaults (and their CHECK constraints) when unset
+    for column, value := range map[string]string{"season": cfg.Season, "country": cfg.Country, "theme": cfg.Theme, "icon": cfg.Icon} {
+        if value != "" { row[column] = value }
+    }
+    return row
+}
+
+func matchKey(group string, week int, round string, leg int, home, away string) string {
+    parts := []string{home + " - " + away}
+    if group != "" { parts = append(parts, group) }
+    if week != 0 { parts = append(parts, "week "+strconv.Itoa(week)) }
+    if round != "" { parts = append(parts, round) }
+    if leg != 0 { parts = append(parts, "leg "+strconv.Itoa(leg)) }
+    return strings.Join(parts, " | ")
+}
+
+// matchKeys adds the day to the keys that still repeat, the games of a
+// playoff series or legs a file does not number
+func matchKeys(bases, days []string) []string {
+    count := make(map[string]int, len(bases))
+    for _, key := range bases {
+        count[key]++
+    }
+    keys := make([]string, len(bases))
+    for i, key := range bases {
+        keys[i] = key
+        if count[key] > 1 { keys[i] = key + " | " + days[i] }
+    }
+    return keys
+}
+
+// matchDay is the local day of a match_date value, "" when there is none
+func matchDay(date interface{}) string {
+    s, _ := date.(string)
+    t, err := time.Parse(time.RFC3339, s)
+    if err != nil { return "" }
+    return t.In(utils.TurkeyTime).Format("2006-01-02")
+}
+
+// matchDate is the kick-off in UTC, midnight local time when only the day is known
+func matchDate(f Fixture) interface{} {
+    t, err := time.ParseInLocation("2006-01-02 15:04", f.Date+" "+f.MatchTime, utils.TurkeyTime)
+    if err != nil {
+        if t, err = time.ParseInLocation("2006-01-02", f.Date, utils.TurkeyTime); err != nil {
+            return nil
+        }
+    }
+    return t.UTC().Format(time.RFC3339)
+}
+
+func matchRecord(leagueID string, f Fixture, uuidOf map[string]interface{}) map[string]interface{} {
+    homeSets, awaySets := utils.SplitSets(utils.ParseSets(f.SetResults))
+
+    status := "scheduled"
+    if f.IsPlayed { status = "finished" }
+    homeScore, awayScore := 0, 0
+    if f.HomeScore != nil { homeScore = *f.HomeScore }
+    if f.AwayScore != nil { awayScore = *f.AwayScore }
+
+    return map[string]interface{}{
+        "league_id":      leagueID,
+        "home_team_id":   uuidOf[f.HomeTeamID],
+        "away_team_id":   uuidOf[f.AwayTeamID],
+        "home_team_name": f.HomeTeam,
+        "away_team_name": f.AwayTeam,
+        "group_name":     nullable(f.GroupName),
+        "week":           nullable(f.Week),
+        "round":          nullable(f.Round),
+        "leg":            nullable(f.Leg),
+        "match_date":     matchDate(f),
+        "match_time":     nullable(f.MatchTime),
+        "venue":          nullable(f.Venue),
+        "status":         status,
+        "home_score":     homeScore,
+        "away_score":     awayScore,
+        "home_sets":      homeSets,
+        "away_sets":      awaySets,
+        "is_played":      f.IsPlayed,
+    }
+}
+
+func standingRecord(leagueID string, s Standing, teamUUID interface{}) map[string]interface{} {
+    return map[string]interface{}{
+        "league_id":       leagueID,
+        "team_id":         teamUUID,
+        "group_name":      nullable(s.GroupName),
+        "matches_played":  s.Played,
+        "wins":            s.Wins,
+        "losses":          s.Losses,
+        "sets_won":        s.SetsWon,
+        "sets_lost":       s.SetsLost,
+        "points_scored":   s.PointsWon,
+        "points_conceded": s.PointsLost,
+        "points":          s.Points,
+        "set_ratio":       numeric(s.SetRatio),
+        "point_ratio":     numeric(s.PointRatio),
+        "rank":            s.Rank,
+        "form":            s.Form,
+    }
+}
@@ -0,0 +1,72 @@
+package league
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "go-backend/database"
+)
+
+func TestSyncDataFiles(t *testing.T) {
+    if err := LoadRegistry(filepath.Join("..", DefaultRegistryPath)); err != nil {
+        t.Fatal(err)
+    }
+    memory := database.NewMemory()
+
+    for _, cfg := range Leagues.All() {
+        l, _, err := LoadFile(filepath.Join("..", "data", cfg.File), cfg.ID)
+        if err != nil {
+            t.Fatalf("%s: %v", cfg.ID, err)
+        }
+        plan, err := Sync(memory, cfg, l, false)
+        if err != nil {
+            t.Fatalf("%s: %v", cfg.ID, err)
+        }
+        want := 0
+        for _, f := range l.Fixture {
+            if !f.GoldenSet { want++ }
+        }
+        if got := plan.Count(SyncInsert); got < want {
+            t.Errorf("%s: %d inserts for %d matches", cfg.ID, got, want)
+        }
+        if rows, _ := memory.Rows("matches", "league_id", cfg.ID); len(rows) != want {
+            t.Errorf("%s: %d match rows, want %d", cfg.ID, len(rows), want)
+        }
+
+        // A second run finds every row in place
+        if plan, err = Sync(memory, cfg, l, false); err != nil || len(plan.Changes) != 0 {
+            t.Errorf("%s: second run %v %+v", cfg.ID, err, plan.Changes)
+        }
+    }
+}
+
+func TestSyncRepeatedPairings(t *testing.T) {
+    cfg := Config{ID: "cup", Name: "Cup"}
+    fixture := func(date string, leg int) Fixture {
+        return Fixture{HomeTeam: "A", AwayTeam: "B", HomeTeamID: "a", AwayTeamID: "b", Round: "Final", Date: date, Leg: leg}
+    }
+    l := &League{ID: "cup", Teams: []Team{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}}}
+    memory := database.NewMemory()
+
+    // Both legs, then a playoff game in the same round without a leg
+    l.Fixture = []Fixture{fixture("2026-03-01", 1), fixture("2026-03-08", 2), fixture("2026-03-12", 0), fixture("2026-03-15", 0)}
+    if _, err := Sync(memory, cfg, l, false); err != nil {
+        t.Fatal(err)
+    }
+    if rows, _ := memory.Rows("matches", "league_id", "cup"); len(rows) != 4 {
+        t.Errorf("%d match rows, want 4", len(rows))
+    }
+    if plan, err := Sync(memory, cfg, l, false); err != nil || len(plan.Changes) != 0 {
+        t.Errorf("second run %v %+v", err, plan.Changes)
+    }
+
+    // The same pairing twice on one day cannot be told apart
+    l.Fixture = append(l.Fixture, fixture("2026-03-15", 0))
+    if _, err := Sync(memory, cfg, l, false); err == nil || !strings.Contains(err.Error(), "two rows") {
+        t.Errorf("duplicate match: %v", err)
+    }
+    if rows, _ := memory.Rows("matches", "league_id", "cup"); len(rows) != 4 {
+        t.Errorf("the failed run left %d match rows", len(rows))
+    }
+}
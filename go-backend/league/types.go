@@ -0,0 +1,122 @@
+package league
+
+import (
+    "fmt"
+    "time"
+
+    "go-backend/utils"
+)
+
+type Team struct {
+    ID        string `json:"id"` // stable team id, see utils.TeamRegistry
+    Name      string `json:"name"`
+    GroupName string `json:"groupName,omitempty"`
+    Country   string `json:"country,omitempty"`
+    Played    int    `json:"played"`
+    Wins      int    `json:"wins"`
+    Points    int    `json:"points"`
+    SetsWon   int    `json:"setsWon"`
+    SetsLost  int    `json:"setsLost"`
+}
+
+type Fixture struct {
+    ID          int    `json:"id,omitempty"`
+    MatchNo     string `json:"matchNo,omitempty"`
+    Date        string `json:"date,omitempty"` // YYYY-MM-DD
+    MatchTime   string `json:"matchTime,omitempty"`
+    Week        int    `json:"week,omitempty"`
+    Round       string `json:"round,omitempty"`
+    GroupName   string `json:"groupName,omitempty"`
+    HomeTeam    string `json:"homeTeam"`
+    AwayTeam    string `json:"awayTeam"`
+    HomeTeamID  string `json:"homeTeamId,omitempty"`
+    AwayTeamID  string `json:"awayTeamId,omitempty"`
+    HomeScore   *int   `json:"homeScore"`
+    AwayScore   *int   `json:"awayScore"`
+    IsPlayed    bool   `json:"isPlayed"`
+    ResultScore string `json:"resultScore,omitempty"`
+    SetResults  string `json:"setResults,omitempty"`
+    Venue       string `json:"venue,omitempty"`
+    City        string `json:"city,omitempty"`
+    Leg         int    `json:"leg,omitempty"`       // two-legged cup ties
+    GoldenSet   bool   `json:"goldenSet,omitempty"` // tie-break set after two legs, not a match result
+}
+
+type League struct {
+    ID          string    `json:"id"`
+    Name        string    `json:"league"`
+    Season      string    `json:"season,omitempty"`
+    Stage       string    `json:"stage,omitempty"`
+    Teams       []Team    `json:"teams"`
+    Fixture     []Fixture `json:"fixture"`
+    LastUpdated string    `json:"lastUpdated,omitempty"`
+
+    scrapedTable bool      // the teams block came with played/wins/points
+    flavour      Flavour   // format of the file
+    raw          []byte    // file contents, for the legacy routes
+    version      string    // hash of the file contents
+    modified     time.Time // file modification time
+}
+
+// Score returns the result as "3-1", or "" for unplayed matches
+func (f Fixture) Score() string {
+    if f.ResultScore != "" {
+        return f.ResultScore
+    }
+    if f.HomeScore != nil && f.AwayScore != nil {
+        return fmt.Sprintf("%d-%d", *f.HomeScore, *f.AwayScore)
+    }
+    return ""
+}
+
+// Version identifies the file contents the league was loaded from
+func (l *League) Version() string {
+    return l.version
+}
+
+func (l *League) Modified() time.Time {
+    return l.modified
+}
+
+// resolveIDs fills in the stable team ids from the registry
+func (l *League) resolveIDs() {
+    for i := range l.Teams {
+        l.Teams[i].ID = utils.ResolveTeam(TeamScope(l.ID), l.Teams[i].Name)
+    }
+    for i := range l.Fixture {
+        l.Fixture[i].HomeTeamID = utils.ResolveTeam(TeamScope(l.ID), l.Fixture[i].HomeTeam)
+        l.Fixture[i].AwayTeamID = utils.ResolveTeam(TeamScope(l.ID), l.Fixture[i].AwayTeam)
+    }
+}
+
+// EloInput converts the league to the rating types. The slices are new,
+// so callers may modify them without touching the shared snapshot.
+func (l *League) EloInput() ([]utils.TeamStats, []utils.Match) {
+    teams := make([]utils.TeamStats, 0, len(l.Teams))
+    for _, t := range l.Teams {
+        teams = append(teams, utils.TeamStats{
+            ID:       t.ID,
+            Name:     t.Name,
+            Points:   t.Points,
+            Wins:     t.Wins,
+            Played:   t.Played,
+            SetsWon:  t.SetsWon,
+            SetsLost: t.SetsLost,
+        })
+    }
+
+    matches := make([]utils.Match, 0, len(l.Fixture))
+    for _, f := range l.Fixture {
+        if f.GoldenSet { continue }
+        matches = append(matches, utils.Match{
+            HomeTeam:    f.HomeTeam,
+            AwayTeam:    f.AwayTeam,
+            ResultScore: f.Score(),
+            IsPlayed:    f.IsPlayed,
+            MatchDate:   f.Date,
+            HomeTeamID:  f.HomeTeamID,
+            AwayTeamID:  f.AwayTeamID,
+        })
+    }
+    return teams, matches
+}
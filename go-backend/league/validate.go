@@ -0,0 +1,189 @@
+package league
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "os"
+    "time"
+
+    "go-backend/utils"
+)
+
+type Issue struct {
+    Match   int    `json:"match"` // index in the fixture, -1 for file-level issues
+    MatchNo string `json:"matchNo,omitempty"`
+    Message string `json:"message"`
+}
+
+// Report lists the problems found in a league file. Errors make the file
+// unusable, warnings are served as-is.
+type Report struct {
+    League   string  `json:"league"`
+    Flavour  Flavour `json:"flavour"`
+    Errors   []Issue `json:"errors"`
+    Warnings []Issue `json:"warnings"`
+}
+
+var ErrInvalid = errors.New("league data failed validation")
+
+func (r *Report) HasErrors() bool {
+    return len(r.Errors) > 0
+}
+
+func (r *Report) errorf(match int, matchNo, format string, args ...interface{}) {
+    r.Errors = append(r.Errors, Issue{match, matchNo, fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) warnf(match int, matchNo, format string, args ...interface{}) {
+    r.Warnings = append(r.Warnings, Issue{match, matchNo, fmt.Sprintf(format, args...)})
+}
+
+// validScore reports whether a best-of-five result is possible
+func validScore(home, away int) bool {
+    return (home == 3 && away >= 0 && away <= 2) || (away == 3 && home >= 0 && home <= 2)
+}
+
+// checkFixtureSets checks a played fixture's set scores against its result,
+// a golden set is a single tie-break length set
+func checkFixtureSets(f Fixture) error {
+    sets := utils.ParseSets(f.SetResults)
+    if f.GoldenSet {
+        if len(sets) != 1 { return fmt.Errorf("%d sets for a golden set", len(sets)) }
+        if err := utils.CheckSet(sets[0], true); err != nil { return err }
+        if (sets[0].Home > sets[0].Away) != (*f.HomeScore == 1) { return fmt.Errorf("golden set winner does not match the score") }
+        return nil
+    }
+    return utils.CheckSets(sets, *f.HomeScore, *f.AwayScore)
+}
+
+// canonicalizeNames rewrites fixture team names that only differ from a
+// team in the teams block by spelling (İ/I, spacing, punctuation).
+func canonicalizeNames(l *League, report *Report) {
+    byKey := make(map[string]string, len(l.Teams))
+    exact := make(map[string]bool, len(l.Teams))
+    for _, t := range l.Teams {
+        byKey[utils.TeamKey(t.Name)] = t.Name
+        exact[t.Name] = true
+    }
+
+    fix := func(i int, name *string) {
+        if exact[*name] {
+            return
+        }
+        if canonical, ok := byKey[utils.TeamKey(*name)]; ok {
+            report.warnf(i, l.Fixture[i].MatchNo, "team %q is spelled %q in the teams block", *name, canonical)
+            *name = canonical
+        }
+    }
+
+    for i := range l.Fixture {
+        fix(i, &l.Fixture[i].HomeTeam)
+        fix(i, &l.Fixture[i].AwayTeam)
+    }
+}
+
+// Validate checks the fixture against the teams block: unknown teams,
+// impossible scores, duplicate match numbers and malformed dates.
+func Validate(l *League, report *Report) {
+    if len(l.Teams) == 0 {
+        report.errorf(-1, "", "no teams")
+    }
+    if len(l.Fixture) == 0 {
+        report.warnf(-1, "", "empty fixture")
+    }
+
+    teams := make(map[string]bool, len(l.Teams))
+    for _, t := range l.Teams {
+        if teams[t.Name] {
+            report.errorf(-1, "", "duplicate team %q", t.Name)
+        }
+        teams[t.Name] = true
+    }
+
+    matchNos := make(map[string]int)
+    ids := make(map[int]int)
+    for i, f := range l.Fixture {
+        for _, name := range []string{f.HomeTeam, f.AwayTeam} {
+            if !teams[name] && !(IsPlaceholder(name) && !f.IsPlayed) {
+                report.errorf(i, f.MatchNo, "unknown team %q", name)
+            }
+        }
+        if f.HomeTeam == f.AwayTeam {
+            report.errorf(i, f.MatchNo, "%q plays itself", f.HomeTeam)
+        }
+
+        if f.MatchNo != "" {
+            if first, ok := matchNos[f.MatchNo]; ok {
+                report.errorf(i, f.MatchNo, "duplicate match number, first used by match %d", first)
+            } else {
+                matchNos[f.MatchNo] = i
+            }
+        }
+        if f.ID != 0 {
+            if first, ok := ids[f.ID]; ok {
+                report.warnf(i, f.MatchNo, "duplicate id %d, first used by match %d", f.ID, first)
+            } else {
+                ids[f.ID] = i
+            }
+        }
+
+        if f.Date != "" {
+            if _, err := time.Parse("2006-01-02", f.Date); err != nil {
+                report.warnf(i, f.MatchNo, "malformed date %q", f.Date)
+            }
+        }
+
+        hasScore := f.HomeScore != nil && f.AwayScore != nil
+        switch {
+        case f.IsPlayed && !hasScore:
+            report.errorf(i, f.MatchNo, "played match without a score")
+        case f.IsPlayed && f.GoldenSet:
+            if *f.HomeScore+*f.AwayScore != 1 {
+                report.errorf(i, f.MatchNo, "impossible golden set score %d-%d", *f.HomeScore, *f.AwayScore)
+            }
+        case f.IsPlayed && !validScore(*f.HomeScore, *f.AwayScore):
+            report.errorf(i, f.MatchNo, "impossible score %d-%d", *f.HomeScore, *f.AwayScore)
+        case !f.IsPlayed && hasScore:
+            report.warnf(i, f.MatchNo, "unplayed match has a score %d-%d", *f.HomeScore, *f.AwayScore)
+        }
+
+        // Set scores are optional, but when given they must add up
+        if f.IsPlayed && hasScore && f.SetResults != "" && (f.GoldenSet || validScore(*f.HomeScore, *f.AwayScore)) {
+            if err := checkFixtureSets(f); err != nil {
+                report.warnf(i, f.MatchNo, "set scores %q: %v", f.SetResults, err)
+            }
+        }
+    }
+}
+
+// LoadFile reads, normalizes and validates one league data file. When the
+// report has errors the league is still returned but err wraps ErrInvalid.
+func LoadFile(path, id string) (*League, *Report, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    l, flavour, err := Parse(id, content)
+    if err != nil {
+        return nil, nil, err
+    }
+    sum := sha256.Sum256(content)
+    l.version = hex.EncodeToString(sum[:8])
+    l.flavour, l.raw = flavour, content
+    if c, ok := Leagues.Get(id); ok && l.Name == "" {
+        l.Name = c.Name
+    }
+
+    report := &Report{League: id, Flavour: flavour, Errors: []Issue{}, Warnings: []Issue{}}
+    canonicalizeNames(l, report)
+    Validate(l, report)
+    l.resolveIDs()
+
+    if report.HasErrors() {
+        return l, report, fmt.Errorf("%w: %d errors, first: %s", ErrInvalid, len(report.Errors), report.Errors[0].Message)
+    }
+    return l, report, nil
+}
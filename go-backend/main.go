@@ -4,25 +4,88 @@ import (
     "log"
     "os"
     "github.com/gofiber/fiber/v2"
+    "github.com/gofiber/fiber/v2/middleware/compress"
     "github.com/gofiber/fiber/v2/middleware/cors"
     "github.com/gofiber/fiber/v2/middleware/logger"
     "github.com/joho/godotenv"
     
     "go-backend/database"
     "go-backend/handlers"
+    "go-backend/league"
     "go-backend/middleware"
+    "go-backend/scheduler"
+    "go-backend/utils"
 )
 
 func main() {
+    // Offline tools (fit-elo, ...) run instead of the server
+    if runCommand(os.Args[1:]) {
+        return
+    }
+
     // Load environment variables
     if err := godotenv.Load(); err != nil {
         log.Println("No .env file found")
     }
 
-    // Initialize Database
-    if err := database.Init(); err != nil {
+    // Load fitted rating parameters
+    eloParamsPath := os.Getenv("ELO_PARAMS_FILE")
+    if eloParamsPath == "" {
+        eloParamsPath = utils.DefaultEloParamsPath
+    }
+    if err := utils.LoadEloParams(eloParamsPath); err != nil {
+        log.Printf("Using default Elo parameters: %v", err)
+    }
+
+    // Load canonical team ids and aliases
+    teamsPath := os.Getenv("TEAM_REGISTRY_FILE")
+    if teamsPath == "" {
+        teamsPath = utils.DefaultTeamRegistryPath
+    }
+    if err := utils.LoadTeamRegistry(teamsPath); err != nil {
+        log.Printf("Team registry not loaded, using name-derived ids: %v", err)
+    }
+
+    // Initialize Database (DATABASE_BACKEND=memory runs without Supabase)
+    repos, err := database.Open()
+    if err != nil {
         log.Fatalf("Failed to initialize database: %v", err)
     }
+    handlers.Init(repos)
+
+    // Load the league registry, optionally taking the list from the leagues table
+    leaguesPath := os.Getenv("LEAGUE_REGISTRY_FILE")
+    if leaguesPath == "" {
+        leaguesPath = league.DefaultRegistryPath
+    }
+    if err := league.LoadRegistry(leaguesPath); err != nil {
+        log.Fatalf("Failed to load league registry: %v", err)
+    }
+    if os.Getenv("LEAGUES_SOURCE") == "database" {
+        if err := league.LoadDatabaseRegistry(repos.Leagues); err != nil {
+            log.Printf("Using %s: %v", leaguesPath, err)
+        }
+    }
+
+    // Load league data into memory (reloaded when the files change)
+    if err := league.Init("data"); err != nil {
+        log.Fatalf("Failed to load league data: %v", err)
+    }
+
+    // Load the scraping and scoring jobs of JOBS_CONFIG_FILE (config/jobs.json).
+    // They run on their schedules only with SCHEDULER=on; otherwise an
+    // external cron calls the /api/cron routes and admins trigger them.
+    jobsPath := os.Getenv("JOBS_CONFIG_FILE")
+    if jobsPath == "" {
+        jobsPath = scheduler.DefaultConfigPath
+    }
+    var locker scheduler.Locker
+    if repos.Locks != nil {
+        locker = repos.Locks // one run at a time across instances
+    }
+    if err := scheduler.Init(jobsPath, handlers.JobFuncs(), locker, os.Getenv("SCHEDULER") == "on"); err != nil {
+        log.Printf("Jobs not loaded: %v", err)
+    }
 
     app := fiber.New(fiber.Config{
         Prefork:       false, // Disable prefork on Windows for development
@@ -32,6 +95,7 @@ func main() {
 
     // Middleware
     app.Use(logger.New())
+    app.Use(compress.New()) // gzip, deflate or brotli from Accept-Encoding
     app.Use(cors.New(cors.Config{
         AllowOrigins: "https://volleysimulator.com.tr,https://www.volleysimulator.com.tr,http://localhost:3000",
         AllowHeaders: "Origin, Content-Type, Accept, Authorization",
@@ -41,12 +105,39 @@ func main() {
     api := app.Group("/api")
     
     // Data endpoints (public)
-    api.Get("/1lig", handlers.Get1Lig)
-    api.Get("/scrape", handlers.Get2Lig)
-    api.Get("/vsl", handlers.GetVSL)
-    api.Get("/cev-cl", handlers.GetCEVCL)
+    // League data answers conditional GETs from the in-memory snapshot version
+    leagueData := middleware.Conditional(handlers.LeagueVersion)
+    allData := middleware.Conditional(handlers.SnapshotVersion)
+
+    api.Get("/leagues", allData, handlers.GetLeagues)
+    api.Get("/leagues/:id", leagueData, handlers.GetLeague)
+    api.Get("/leagues/:id/seasons", allData, handlers.GetSeasons)
+    api.Get("/data/status", handlers.GetDataStatus)
+    api.Get("/changes", handlers.GetChanges) // ?since=<cursor or time>
     api.Get("/leaderboard", handlers.GetLeaderboard) // Leaderboard can be public
+    api.Get("/leagues/:id/matches", leagueData, handlers.GetMatches)
+    api.Get("/leagues/:id/standings", leagueData, handlers.GetStandings)
+    api.Get("/leagues/:id/calendar.ics", leagueData, handlers.GetLeagueCalendar)
+    api.Get("/leagues/:id/teams/:team/ratings", leagueData, handlers.GetTeamRatings)
+    api.Get("/leagues/:id/schedule-strength", leagueData, handlers.GetScheduleStrength)
+    api.Get("/power-rankings", allData, handlers.GetPowerRankings)
+    api.Get("/teams/:slug", allData, handlers.GetTeam)
+    api.Get("/teams/:slug/calendar.ics", allData, handlers.GetTeamCalendar)
+    api.Get("/h2h", allData, handlers.GetHeadToHead)
+    api.Get("/export/:dataset", handlers.GetExport)
+
+    // Legacy per-league routes from the registry (/api/vsl, /api/scrape, ...)
+    for _, cfg := range league.Leagues.All() {
+        if cfg.APIEndpoint != "" {
+            app.Get(cfg.APIEndpoint, middleware.Conditional(handlers.AliasVersion(cfg.ID)), handlers.LeagueAlias(cfg.ID))
+        }
+    }
     
+    // Cron jobs, authorized by CRON_SECRET instead of a user token, so they
+    // go before the protected group's middleware
+    api.Post("/results/sync", handlers.SyncResults)
+    api.Post("/cron/update-results", handlers.UpdateResults)
+
     // Protected endpoints
     protected := api.Group("/", middleware.AuthRequired())
     protected.Get("/predictions", handlers.GetPredictions)
@@ -57,15 +148,17 @@ func main() {
     protected.Post("/calculate", handlers.Calculate)
     protected.Post("/predict-all", handlers.PredictAll)
 
-    // Cron jobs
-
-    api.Post("/results/sync", handlers.SyncResults)
-    api.Post("/cron/update-results", handlers.UpdateResults)
 
     // Admin endpoints
     admin := api.Group("/admin", middleware.AuthRequired(), middleware.AdminOnly())
     admin.Get("/stats", handlers.GetAdminStats)
     admin.Post("/match/update", handlers.UpdateMatchResult)
+    admin.Get("/jobs", handlers.GetJobs)
+    admin.Get("/jobs/runs", handlers.GetJobRuns)
+    admin.Get("/jobs/:name/runs", handlers.GetJobRuns)
+    admin.Post("/jobs/:name/run", handlers.TriggerJob)
+    admin.Post("/jobs/:name/pause", handlers.PauseJob)
+    admin.Post("/jobs/:name/resume", handlers.ResumeJob)
 
     port := os.Getenv("PORT")
     if port == "" {
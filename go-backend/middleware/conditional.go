@@ -0,0 +1,70 @@
+package middleware
+
+import (
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gofiber/fiber/v2"
+)
+
+// Changes on every start, so cached responses don't outlive new Elo
+// parameters or team registry entries
+var (
+    bootTime = time.Now().Truncate(time.Second)
+    bootID   = strconv.FormatInt(bootTime.Unix(), 36)
+)
+
+// VersionFunc returns the version of the data a request is answered from
+type VersionFunc func(c *fiber.Ctx) (version string, modified time.Time, ok bool)
+
+// Conditional sets ETag and Last-Modified from the data version and answers
+// 304 when the client's copy is still current, without running the handler.
+func Conditional(versionOf VersionFunc) fiber.Handler {
+    return func(c *fiber.Ctx) error {
+        if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+            return c.Next()
+        }
+
+        version, modified, ok := versionOf(c)
+        if !ok {
+            return c.Next()
+        }
+
+        etag := `W/"` + bootID + "-" + version + `"`
+        c.Set(fiber.HeaderETag, etag)
+        c.Set(fiber.HeaderCacheControl, "no-cache")
+        if !modified.IsZero() {
+            // Responses also depend on what was loaded at startup, as the ETag does
+            if modified.Before(bootTime) { modified = bootTime }
+            c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+        }
+
+        // If-None-Match wins over If-Modified-Since when both are sent
+        if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+            if etagMatches(match, etag) {
+                return c.SendStatus(fiber.StatusNotModified)
+            }
+            return c.Next()
+        }
+        if since := c.Get(fiber.HeaderIfModifiedSince); since != "" && !modified.IsZero() {
+            if t, err := http.ParseTime(since); err == nil && !modified.Truncate(time.Second).After(t) {
+                return c.SendStatus(fiber.StatusNotModified)
+            }
+        }
+        return c.Next()
+    }
+}
+
+// etagMatches compares weakly, as If-None-Match requires
+func etagMatches(header, etag string) bool {
+    etag = strings.TrimPrefix(etag, "W/")
+    for _, candidate := range strings.Split(header, ",") {
+        candidate = strings.TrimSpace(candidate)
+        if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+            return true
+        }
+    }
+    return false
+}
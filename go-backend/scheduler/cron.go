@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Schedule is a parsed five field cron expression (minute hour day-of-month
+// month day-of-week), each field a bit set of the values it matches
+type Schedule struct {
+    minute, hour, dom, month, dow uint64
+    domAny, dowAny               bool
+}
+
+var descriptors = map[string]string{
+    "@hourly":  "0 * * * *",
+    "@daily":   "0 0 * * *",
+    "@weekly":  "0 0 * * 1",
+    "@monthly": "0 0 1 * *",
+}
+
+type field struct {
+    min, max int
+}
+
+var fields = []field{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// ParseSchedule reads an expression such as "*/15 8-23 * * 1-5". Fields take
+// *, numbers, ranges, lists and /steps; day of week 0 and 7 are both Sunday.
+// @hourly, @daily, @weekly (Monday) and @monthly are accepted as shorthands.
+func ParseSchedule(expr string) (*Schedule, error) {
+    if full, ok := descriptors[strings.TrimSpace(expr)]; ok { expr = full }
+    parts := strings.Fields(expr)
+    if len(parts) != 5 {
+        return nil, fmt.Errorf("cron expression %q needs 5 fields", expr)
+    }
+
+    sets := make([]uint64, 5)
+    for i, part := range parts {
+        set, err := parseField(part, fields[i])
+        if err != nil {
+            return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+        }
+        sets[i] = set
+    }
+    if sets[4]&(1<<7) != 0 { sets[4] |= 1 }
+
+    return &Schedule{
+        minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+        domAny: parts[2] == "*", dowAny: parts[4] == "*",
+    }, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+    var set uint64
+    for _, item := range strings.Split(s, ",") {
+        rng, step := item, 1
+        if i := strings.IndexByte(item, '/'); i >= 0 {
+            n, err := strconv.Atoi(item[i+1:])
+            if err != nil || n < 1 {
+                return 0, fmt.Errorf("bad step in %q", item)
+            }
+            rng, step = item[:i], n
+        }
+
+        lo, hi := f.min, f.max
+        if rng != "*" {
+            bounds := strings.SplitN(rng, "-", 2)
+            var err error
+            if lo, err = strconv.Atoi(bounds[0]); err != nil {
+                return 0, fmt.Errorf("bad value %q", item)
+            }
+            hi = lo
+            if len(bounds) == 2 {
+                if hi, err = strconv.Atoi(bounds[1]); err != nil {
+                    return 0, fmt.Errorf("bad range %q", item)
+                }
+            } else if step > 1 {
+                hi = f.max // "5/15" runs from 5 to the end
+            }
+        }
+        if lo < f.min || hi > f.max || lo > hi {
+            return 0, fmt.Errorf("%q out of range %d-%d", item, f.min, f.max)
+        }
+        for v := lo; v <= hi; v += step {
+            set |= 1 << uint(v)
+        }
+    }
+    return set, nil
+}
+
+func has(set uint64, v int) bool {
+    return set&(1<<uint(v)) != 0
+}
+
+// day applies cron's rule that a restricted day of month and day of week
+// match when either does
+func (s *Schedule) day(t time.Time) bool {
+    dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+    if s.domAny || s.dowAny { return dom && dow }
+    return dom || dow
+}
+
+// Next returns the first matching minute after t, in t's location. Zero
+// when nothing matches within five years (such as "0 0 30 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+    t = t.Truncate(time.Minute).Add(time.Minute)
+    limit := t.AddDate(5, 0, 0)
+    for t.Before(limit) {
+        switch {
+        case !has(s.month, int(t.Month())):
+            t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+        case !s.day(t):
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+        case !has(s.hour, t.Hour()):
+            t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+        case !has(s.minute, t.Minute()):
+            t = t.Add(time.Minute)
+        default:
+            return t
+        }
+    }
+    return time.Time{}
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+    "testing"
+    "time"
+)
+
+func TestScheduleNext(t *testing.T) {
+    at := func(s string) time.Time {
+        v, err := time.Parse("2006-01-02 15:04", s)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return v
+    }
+
+    for _, tc := range []struct {
+        expr, from, want string
+    }{
+        {"*/15 * * * *", "2026-10-20 10:07", "2026-10-20 10:15"},
+        {"*/15 * * * *", "2026-10-20 10:45", "2026-10-20 11:00"},
+        {"5/15 * * * *", "2026-10-20 10:00", "2026-10-20 10:05"},
+        {"5/15 * * * *", "2026-10-20 10:05", "2026-10-20 10:20"},
+        {"5/15 * * * *", "2026-10-20 10:50", "2026-10-20 11:05"},
+        {"0 8-10,14 * * *", "2026-10-20 10:30", "2026-10-20 14:00"},
+        {"0 8-10,14 * * *", "2026-10-20 14:00", "2026-10-21 08:00"},
+        {"30 9 * * 1-5", "2026-10-17 12:00", "2026-10-19 09:30"},
+        // 7 and 0 are both Sunday
+        {"0 9 * * 7", "2026-10-14 12:00", "2026-10-18 09:00"},
+        {"0 9 * * 0", "2026-10-14 12:00", "2026-10-18 09:00"},
+        // A restricted day of month and day of week match when either does
+        {"0 0 1 * 1", "2026-10-20 12:00", "2026-10-26 00:00"},
+        {"0 0 1 * 1", "2026-10-27 12:00", "2026-11-01 00:00"},
+        {"0 0 1 * *", "2026-10-20 12:00", "2026-11-01 00:00"},
+        {"@weekly", "2026-10-20 12:00", "2026-10-26 00:00"},
+        {"@monthly", "2026-12-05 00:00", "2027-01-01 00:00"},
+    } {
+        s, err := ParseSchedule(tc.expr)
+        if err != nil {
+            t.Errorf("%q: %v", tc.expr, err)
+            continue
+        }
+        if got := s.Next(at(tc.from)); !got.Equal(at(tc.want)) {
+            t.Errorf("%q after %s = %s, want %s", tc.expr, tc.from, got.Format("2006-01-02 15:04"), tc.want)
+        }
+    }
+
+    // February never has a 30th
+    s, err := ParseSchedule("0 0 30 2 *")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if next := s.Next(at("2026-10-20 12:00")); !next.IsZero() {
+        t.Errorf("0 0 30 2 * runs at %s", next)
+    }
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+    for _, expr := range []string{
+        "",
+        "* * * *",
+        "* * * * * *",
+        "60 * * * *",
+        "* 24 * * *",
+        "* * 0 * *",
+        "* * * 13 *",
+        "* * * * 8",
+        "10-5 * * * *",
+        "*/0 * * * *",
+        "a * * * *",
+        "1-x * * * *",
+        "@yearly",
+    } {
+        if _, err := ParseSchedule(expr); err == nil {
+            t.Errorf("%q parsed", expr)
+        }
+    }
+}
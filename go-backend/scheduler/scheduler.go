@@ -0,0 +1,439 @@
+// Package scheduler runs the scraping and scoring jobs of config/jobs.json
+// (JOBS_CONFIG_FILE overrides the path) inside the API process. The jobs are
+// always loaded so they can be triggered from the admin endpoints and the
+// cron routes, but they only run on their schedules with SCHEDULER=on; the
+// default leaves them to an external cron. With historyFile set in the
+// config, finished runs are appended to that JSONL file and survive restarts.
+package scheduler
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "sort"
+    "sync"
+    "time"
+
+    "go-backend/utils"
+)
+
+// Func is the work of a job. The summary is kept in the run history.
+type Func func(ctx context.Context) (summary string, err error)
+
+type JobConfig struct {
+    Name     string `json:"name"`
+    Schedule string `json:"schedule"`
+    Paused   bool   `json:"paused,omitempty"`
+    Timeout  string `json:"timeout,omitempty"` // Go duration, default 10m
+}
+
+type Config struct {
+    Timezone    string      `json:"timezone,omitempty"`    // schedules are read in this zone, default Europe/Istanbul
+    HistoryFile string      `json:"historyFile,omitempty"` // JSONL file finished runs are kept in, memory only when empty
+    Jobs        []JobConfig `json:"jobs"`
+}
+
+const DefaultConfigPath = "config/jobs.json"
+
+// Run outcomes
+const (
+    Running   = "running"
+    Succeeded = "succeeded"
+    Failed    = "failed"
+    Skipped   = "skipped" // another instance held the job's lock
+)
+
+// Run is one execution of a job
+type Run struct {
+    ID      int        `json:"id"`
+    Job     string     `json:"job"`
+    Trigger string     `json:"trigger"` // schedule, manual or cron
+    Start   time.Time  `json:"start"`
+    End     *time.Time `json:"end,omitempty"`
+    Outcome string     `json:"outcome"`
+    Summary string     `json:"summary,omitempty"`
+    Error   string     `json:"error,omitempty"`
+}
+
+// Job is the state of a job as the admin endpoints show it
+type Job struct {
+    Name     string     `json:"name"`
+    Schedule string     `json:"schedule"`
+    Paused   bool       `json:"paused"`
+    Running  bool       `json:"running"`
+    Next     *time.Time `json:"next,omitempty"`
+    LastRun  *Run       `json:"lastRun,omitempty"`
+}
+
+var (
+    ErrUnknownJob = errors.New("unknown job")
+    ErrRunning    = errors.New("job is already running")
+    ErrLocked     = errors.New("job is running on another instance")
+)
+
+// Locker keeps a job from running on two instances of the API at once, as
+// the postgres backend does with advisory locks. TryLock reports false when
+// another instance holds the key; release frees it.
+type Locker interface {
+    TryLock(ctx context.Context, key string) (release func(), ok bool, err error)
+}
+
+type job struct {
+    config   JobConfig
+    schedule *Schedule
+    timeout  time.Duration
+    fn       Func
+    paused   bool
+    running  *Run
+    next     time.Time
+}
+
+// historySize is how many runs are kept, over all jobs
+const historySize = 200
+
+// Scheduler runs jobs on their cron schedules. A job never overlaps itself:
+// a scheduled run is skipped and a manual one refused while it is running,
+// on this instance and, with a Locker, on the others.
+type Scheduler struct {
+    mu          sync.Mutex
+    jobs        map[string]*job
+    order       []string
+    loc         *time.Location
+    history     []Run // oldest first
+    nextID      int
+    historyFile string
+    locker      Locker
+    started     bool
+}
+
+// Jobs is the scheduler loaded by Init, nil when its config did not load
+var Jobs *Scheduler
+
+// Init loads the job config and makes it the package's Jobs. The jobs run on
+// their schedules only with start; locker may be nil for a single instance.
+func Init(path string, funcs map[string]Func, locker Locker, start bool) error {
+    s, err := Load(path, funcs)
+    if err != nil {
+        return err
+    }
+    s.locker = locker
+    Jobs = s
+    if start { go s.Start(context.Background()) }
+    return nil
+}
+
+// Load reads a job config. Every configured job needs a function.
+func Load(path string, funcs map[string]Func) (*Scheduler, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var cfg Config
+    if err := json.Unmarshal(content, &cfg); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return New(cfg, funcs)
+}
+
+func New(cfg Config, funcs map[string]Func) (*Scheduler, error) {
+    loc := utils.TurkeyTime
+    if cfg.Timezone != "" {
+        var err error
+        if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+            log.Printf("Time zone %s not found, using Europe/Istanbul: %v", cfg.Timezone, err)
+            loc = utils.TurkeyTime
+        }
+    }
+
+    s := &Scheduler{jobs: make(map[string]*job), loc: loc, historyFile: cfg.HistoryFile}
+    if s.historyFile != "" {
+        if err := s.loadHistory(); err != nil {
+            log.Printf("Job history %s not loaded: %v", s.historyFile, err)
+        }
+    }
+    for _, jc := range cfg.Jobs {
+        fn, ok := funcs[jc.Name]
+        if !ok {
+            return nil, fmt.Errorf("job %q: %w", jc.Name, ErrUnknownJob)
+        }
+        if _, dup := s.jobs[jc.Name]; dup {
+            return nil, fmt.Errorf("job %q configured twice", jc.Name)
+        }
+        schedule, err := ParseSchedule(jc.Schedule)
+        if err != nil {
+            return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+        }
+        timeout := 10 * time.Minute
+        if jc.Timeout != "" {
+            if timeout, err = time.ParseDuration(jc.Timeout); err != nil {
+                return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+            }
+        }
+        s.jobs[jc.Name] = &job{config: jc, schedule: schedule, timeout: timeout, fn: fn, paused: jc.Paused}
+        s.order = append(s.order, jc.Name)
+    }
+    return s, nil
+}
+
+// Start runs due jobs every minute until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+    s.mu.Lock()
+    s.started = true
+    now := time.Now().In(s.loc)
+    for _, j := range s.jobs {
+        j.next = j.schedule.Next(now)
+    }
+    s.mu.Unlock()
+
+    for {
+        // Wake at the start of the next minute
+        now := time.Now()
+        timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case now = <-timer.C:
+        }
+
+        s.mu.Lock()
+        var due []string
+        for _, name := range s.order {
+            j := s.jobs[name]
+            if j.next.IsZero() || now.Before(j.next) { continue }
+            j.next = j.schedule.Next(now.In(s.loc))
+            if j.paused { continue }
+            if j.running != nil {
+                log.Printf("Job %s still running, skipping scheduled run", name)
+                continue
+            }
+            due = append(due, name)
+        }
+        s.mu.Unlock()
+
+        for _, name := range due {
+            s.start(name, "schedule", nil, false)
+        }
+    }
+}
+
+// Trigger starts a job now, outside its schedule and even when paused
+func (s *Scheduler) Trigger(name string) (Run, error) {
+    return s.start(name, "manual", nil, false)
+}
+
+// Exclusive runs fn in place of a job's function and waits for it, taking
+// the job's single-run slot like a triggered run, for callers that need
+// more than the summary. It fails with ErrRunning or ErrLocked while the
+// job runs here or elsewhere.
+func (s *Scheduler) Exclusive(name, trigger string, fn Func) (Run, error) {
+    return s.start(name, trigger, fn, true)
+}
+
+func (s *Scheduler) start(name, trigger string, fn Func, wait bool) (Run, error) {
+    s.mu.Lock()
+    j, ok := s.jobs[name]
+    if !ok {
+        s.mu.Unlock()
+        return Run{}, ErrUnknownJob
+    }
+    if j.running != nil {
+        run := *j.running
+        s.mu.Unlock()
+        return run, ErrRunning
+    }
+    s.nextID++
+    run := &Run{ID: s.nextID, Job: j.config.Name, Trigger: trigger, Start: time.Now(), Outcome: Running}
+    j.running = run
+    s.mu.Unlock()
+
+    if fn == nil { fn = j.fn }
+    if !wait {
+        go s.execute(j, run, fn)
+        return *run, nil
+    }
+
+    s.execute(j, run, fn)
+    s.mu.Lock()
+    finished := *run
+    s.mu.Unlock()
+    if finished.Outcome == Skipped {
+        return finished, ErrLocked
+    }
+    return finished, nil
+}
+
+func (s *Scheduler) execute(j *job, run *Run, fn Func) {
+    ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+    defer cancel()
+
+    summary, err := func() (summary string, err error) {
+        defer func() {
+            if r := recover(); r != nil { err = fmt.Errorf("panic: %v", r) }
+        }()
+        if s.locker != nil {
+            release, ok, err := s.locker.TryLock(ctx, "job:"+j.config.Name)
+            if err != nil {
+                return "", fmt.Errorf("taking the job lock: %w", err)
+            }
+            if !ok {
+                return "", ErrLocked
+            }
+            defer release()
+        }
+        return fn(ctx)
+    }()
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    end := time.Now()
+    run.End, run.Summary, run.Outcome = &end, summary, Succeeded
+    switch {
+    case errors.Is(err, ErrLocked):
+        run.Outcome, run.Summary = Skipped, err.Error()
+    case err != nil:
+        run.Outcome, run.Error = Failed, err.Error()
+        log.Printf("Job %s failed: %v", run.Job, err)
+    }
+    j.running = nil
+    s.history = append(s.history, *run)
+    if len(s.history) > historySize { s.history = s.history[len(s.history)-historySize:] }
+    s.saveRun(*run)
+}
+
+// loadHistory reads the runs of earlier processes from the history file,
+// keeping the newest and rewriting the file when it has grown past them
+func (s *Scheduler) loadHistory() error {
+    f, err := os.Open(s.historyFile)
+    if os.IsNotExist(err) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    lines := 0
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        var run Run
+        if err := json.Unmarshal(scanner.Bytes(), &run); err != nil || run.End == nil { continue }
+        lines++
+        s.history = append(s.history, run)
+        if run.ID > s.nextID { s.nextID = run.ID }
+    }
+    if err := scanner.Err(); err != nil {
+        return err
+    }
+    if len(s.history) > historySize { s.history = s.history[len(s.history)-historySize:] }
+    if lines > 2*historySize {
+        return s.rewriteHistory()
+    }
+    return nil
+}
+
+func (s *Scheduler) rewriteHistory() error {
+    tmp := s.historyFile + ".tmp"
+    f, err := os.Create(tmp)
+    if err != nil {
+        return err
+    }
+    enc := json.NewEncoder(f)
+    for _, run := range s.history {
+        enc.Encode(run)
+    }
+    if err := f.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp, s.historyFile)
+}
+
+// saveRun appends a finished run to the history file, failures are only logged
+func (s *Scheduler) saveRun(run Run) {
+    if s.historyFile == "" { return }
+    f, err := os.OpenFile(s.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+    if err == nil {
+        err = json.NewEncoder(f).Encode(run)
+        if closeErr := f.Close(); err == nil { err = closeErr }
+    }
+    if err != nil {
+        log.Printf("Job history %s: %v", s.historyFile, err)
+    }
+}
+
+// Started tells whether the jobs run on their schedules
+func (s *Scheduler) Started() bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.started
+}
+
+// PersistentHistory tells whether runs survive a restart
+func (s *Scheduler) PersistentHistory() bool {
+    return s.historyFile != ""
+}
+
+// SetPaused stops or resumes a job's scheduled runs, manual triggers still work
+func (s *Scheduler) SetPaused(name string, paused bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    j, ok := s.jobs[name]
+    if !ok { return ErrUnknownJob }
+    j.paused = paused
+    return nil
+}
+
+// List returns the jobs in config order
+func (s *Scheduler) List() []Job {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    jobs := make([]Job, 0, len(s.order))
+    for _, name := range s.order {
+        j := s.jobs[name]
+        state := Job{Name: name, Schedule: j.config.Schedule, Paused: j.paused, Running: j.running != nil}
+        if !j.next.IsZero() && !j.paused {
+            next := j.next
+            state.Next = &next
+        }
+        if j.running != nil {
+            run := *j.running
+            state.LastRun = &run
+        } else {
+            for i := len(s.history) - 1; i >= 0; i-- {
+                if s.history[i].Job == name {
+                    run := s.history[i]
+                    state.LastRun = &run
+                    break
+                }
+            }
+        }
+        jobs = append(jobs, state)
+    }
+    return jobs
+}
+
+// History returns the runs of a job (all jobs for ""), newest first,
+// including the one in progress
+func (s *Scheduler) History(name string, limit int) ([]Run, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.jobs[name]; name != "" && !ok {
+        return nil, ErrUnknownJob
+    }
+    runs := make([]Run, 0)
+    for _, j := range s.jobs {
+        if j.running != nil && (name == "" || j.running.Job == name) { runs = append(runs, *j.running) }
+    }
+    for i := len(s.history) - 1; i >= 0; i-- {
+        if name == "" || s.history[i].Job == name { runs = append(runs, s.history[i]) }
+    }
+    sort.SliceStable(runs, func(a, b int) bool { return runs[a].ID > runs[b].ID })
+    if limit > 0 && len(runs) > limit { runs = runs[:limit] }
+    return runs, nil
+}
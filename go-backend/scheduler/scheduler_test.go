@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+    "context"
+    "errors"
+    "path/filepath"
+    "testing"
+)
+
+// heldLock is a Locker whose keys another instance holds
+type heldLock map[string]bool
+
+func (h heldLock) TryLock(ctx context.Context, key string) (func(), bool, error) {
+    if h[key] {
+        return nil, false, nil
+    }
+    return func() {}, true, nil
+}
+
+func testScheduler(t *testing.T, history string) *Scheduler {
+    t.Helper()
+    s, err := New(Config{HistoryFile: history, Jobs: []JobConfig{{Name: "scrape", Schedule: "@hourly"}}},
+        map[string]Func{"scrape": func(ctx context.Context) (string, error) { return "scheduled", nil }})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return s
+}
+
+func TestExclusive(t *testing.T) {
+    s := testScheduler(t, "")
+
+    // A run in the job's slot refuses another one
+    var inner error
+    run, err := s.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) {
+        _, inner = s.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) { return "", nil })
+        return "done", nil
+    })
+    if err != nil || run.Outcome != Succeeded || run.Summary != "done" || run.Trigger != "cron" {
+        t.Fatalf("run %+v, err %v", run, err)
+    }
+    if !errors.Is(inner, ErrRunning) {
+        t.Errorf("second run while the first one runs: %v, want ErrRunning", inner)
+    }
+
+    // Failures of fn are the run's
+    run, err = s.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) { return "", errors.New("page down") })
+    if err != nil || run.Outcome != Failed || run.Error != "page down" {
+        t.Errorf("failed run %+v, err %v", run, err)
+    }
+
+    if _, err := s.Exclusive("missing", "cron", nil); !errors.Is(err, ErrUnknownJob) {
+        t.Errorf("unknown job: %v", err)
+    }
+}
+
+func TestLockedElsewhere(t *testing.T) {
+    s := testScheduler(t, "")
+    s.locker = heldLock{"job:scrape": true}
+
+    ran := false
+    run, err := s.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) { ran = true; return "", nil })
+    if !errors.Is(err, ErrLocked) || run.Outcome != Skipped || ran {
+        t.Errorf("run %+v, err %v, ran %t", run, err, ran)
+    }
+}
+
+func TestHistoryFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "runs.jsonl")
+    s := testScheduler(t, path)
+    s.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) { return "first", nil })
+    s.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) { return "second", nil })
+
+    // A restart reads the runs back and continues their ids
+    restarted := testScheduler(t, path)
+    runs, _ := restarted.History("scrape", 0)
+    if len(runs) != 2 || runs[0].Summary != "second" || runs[1].Summary != "first" {
+        t.Fatalf("history after restart: %+v", runs)
+    }
+    run, _ := restarted.Exclusive("scrape", "cron", func(ctx context.Context) (string, error) { return "", nil })
+    if run.ID != 3 {
+        t.Errorf("run id %d after restart, want 3", run.ID)
+    }
+    if !restarted.PersistentHistory() || testScheduler(t, "").PersistentHistory() {
+        t.Error("PersistentHistory does not follow historyFile")
+    }
+}
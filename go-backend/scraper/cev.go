@@ -0,0 +1,241 @@
+package scraper
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "golang.org/x/net/html"
+
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+// CEVCompetitions are the women's competition pages by league id, under the
+// final year of the season (2026 for 2025-2026)
+var CEVCompetitions = map[string]string{
+    "cev-cl":        "https://www.cev.eu/club/champions-league/%s/women/",
+    "cev-cup":       "https://www.cev.eu/club/volleyball-cup/%s/women/",
+    "cev-challenge": "https://www.cev.eu/club/volleyball-challenge-cup/%s/women/",
+}
+
+// CEVURL is a competition's page for a season such as "2025-2026", or for
+// the season under way (from July) when season has no final year
+func CEVURL(id, season string) (string, bool) {
+    page, ok := CEVCompetitions[id]
+    if !ok {
+        return "", false
+    }
+    year := season[strings.LastIndex(season, "-")+1:]
+    if _, err := strconv.Atoi(year); err != nil || len(year) != 4 {
+        now := time.Now()
+        y := now.Year()
+        if now.Month() >= time.July { y++ }
+        year = strconv.Itoa(y)
+    }
+    return fmt.Sprintf(page, year), true
+}
+
+var (
+    cevDatePattern    = regexp.MustCompile(`(\d{2})/(\d{2})/(\d{4})(?:\s+(\d{2}:\d{2}))?`)
+    cevLegPattern     = regexp.MustCompile(`\bLeg\s+(\d)`)
+    cevMatchNoPattern = regexp.MustCompile(`Match\s+([A-Z]+\s*\d+)`)
+    cevScorePattern   = regexp.MustCompile(`(\d+)\s*-\s*(\d+)`)
+)
+
+// FetchCEV downloads and parses a CEV competition page
+func FetchCEV(ctx context.Context, id, url string) (*league.League, error) {
+    body, err := fetch(ctx, url)
+    if err != nil {
+        return nil, err
+    }
+    defer body.Close()
+    return ParseCEV(id, body)
+}
+
+// ParseCEV reads every match summary of a CEV competition page into the
+// typed model. Headings give the context of the summaries after them: h2 is
+// the round ("4th Round", "8th Finals"), an h3 starting with "Pool" the
+// group and other h3/h4 headings a part of the round ("Leg 1"), joined as
+// "4th Round | Leg 1". Two-legged ties take their leg from the summary's
+// info line, and a golden set becomes a fixture of its own after the leg it
+// decided, scored 1-0 like the data files.
+func ParseCEV(id string, r io.Reader) (*league.League, error) {
+    doc, err := html.Parse(r)
+    if err != nil {
+        return nil, err
+    }
+
+    var fixture []league.Fixture
+    countries := make(map[string]string) // team key -> country code
+    round, part, group := "", "", ""
+    var walk func(n *html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.ElementNode {
+            switch {
+            case n.Data == "h2":
+                round, part, group = text(n), "", ""
+                return
+            case n.Data == "h3" && strings.HasPrefix(text(n), "Pool "):
+                group, part = text(n), ""
+                return
+            case n.Data == "h3" || n.Data == "h4":
+                part = text(n)
+                return
+            case hasClass(n, "c-match-summary"):
+                name := round
+                if part != "" { name += " | " + part }
+                fixture = append(fixture, parseCEVMatch(n, name, group, countries)...)
+                return
+            }
+        }
+        for c := n.FirstChild; c != nil; c = c.NextSibling {
+            walk(c)
+        }
+    }
+    walk(doc)
+
+    if len(fixture) == 0 {
+        return nil, fmt.Errorf("no match summaries on the page")
+    }
+
+    l := &league.League{ID: id, Fixture: fixture}
+    if c, ok := league.Leagues.Get(id); ok { l.Name = c.Name }
+    for i := range l.Fixture {
+        l.Fixture[i].ID = i + 1
+        if l.Stage == "" && !l.Fixture[i].IsPlayed {
+            l.Stage = strings.SplitN(l.Fixture[i].Round, " | ", 2)[0]
+        }
+    }
+    if l.Stage == "" { l.Stage = strings.SplitN(fixture[len(fixture)-1].Round, " | ", 2)[0] }
+
+    // The table is derived like for files without one, pools and countries
+    // come from the fixtures
+    l.Teams = league.DeriveTeams(l.Fixture)
+    groups := make(map[string]string)
+    for _, f := range l.Fixture {
+        if f.GroupName == "" { continue }
+        groups[utils.TeamKey(f.HomeTeam)] = f.GroupName
+        groups[utils.TeamKey(f.AwayTeam)] = f.GroupName
+    }
+    for i := range l.Teams {
+        key := utils.TeamKey(l.Teams[i].Name)
+        l.Teams[i].GroupName, l.Teams[i].Country = groups[key], countries[key]
+    }
+    l.LastUpdated = time.Now().UTC().Format(time.RFC3339)
+    return l, nil
+}
+
+// parseCEVMatch reads one match summary, and its golden set when it had one
+func parseCEVMatch(n *html.Node, round, group string, countries map[string]string) []league.Fixture {
+    teams := findAll(n, func(c *html.Node) bool { return hasClass(c, "c-match-summary__teamSummary") })
+    if len(teams) < 2 { return nil }
+
+    home, away := cevTeam(teams[0], countries), cevTeam(teams[1], countries)
+    info := classText(n, "c-match-summary__info")
+    f := league.Fixture{
+        Round:     round,
+        GroupName: group,
+        HomeTeam:  home,
+        AwayTeam:  away,
+        Venue:     classText(n, "c-match-summary__venue"),
+    }
+    if m := cevDatePattern.FindStringSubmatch(info); m != nil {
+        f.Date, f.MatchTime = m[3]+"-"+m[2]+"-"+m[1], m[4]
+    }
+    if m := cevLegPattern.FindStringSubmatch(info); m != nil {
+        f.Leg, _ = strconv.Atoi(m[1])
+    }
+    if m := cevMatchNoPattern.FindStringSubmatch(info); m != nil {
+        f.MatchNo = m[1]
+    }
+    // A match in progress shows its score so far, only a finished one is a
+    // result. Sets that do not add up are kept for validation to report.
+    if m := cevScorePattern.FindStringSubmatch(classText(n, "c-match-summary__score")); m != nil {
+        h, _ := strconv.Atoi(m[1])
+        a, _ := strconv.Atoi(m[2])
+        if finished(h, a) {
+            f.HomeScore, f.AwayScore, f.IsPlayed = &h, &a, true
+            f.ResultScore = f.Score()
+            f.SetResults = utils.FormatSets(utils.ParseSets(classText(n, "c-match-summary__sets")))
+        }
+    }
+    fixtures := []league.Fixture{f}
+
+    if m := cevScorePattern.FindStringSubmatch(classText(n, "c-match-summary__golden-set")); m != nil {
+        h, _ := strconv.Atoi(m[1])
+        a, _ := strconv.Atoi(m[2])
+        won, lost := 1, 0
+        if a > h { won, lost = 0, 1 }
+        golden := league.Fixture{
+            Date:       f.Date,
+            Round:      round,
+            GroupName:  group,
+            HomeTeam:   home,
+            AwayTeam:   away,
+            HomeScore:  &won,
+            AwayScore:  &lost,
+            IsPlayed:   true,
+            SetResults: utils.FormatSets([]utils.SetScore{{Home: h, Away: a}}),
+            Leg:        f.Leg,
+            GoldenSet:  true,
+        }
+        golden.ResultScore = golden.Score()
+        if f.MatchNo != "" { golden.MatchNo = f.MatchNo + " GS" }
+        fixtures = append(fixtures, golden)
+    }
+    return fixtures
+}
+
+// cevTeam is the name of a team summary without the country code shown next
+// to it, which is remembered for the teams block
+func cevTeam(n *html.Node, countries map[string]string) string {
+    country := classText(n, "c-match-summary__country")
+    name := classText(n, "c-match-summary__teamName")
+    if name == "" { name = strings.TrimSpace(strings.TrimSuffix(text(n), country)) }
+    if country != "" { countries[utils.TeamKey(name)] = country }
+    return name
+}
+
+// classText is the text of the first element below n with a class
+func classText(n *html.Node, class string) string {
+    if found := find(n, func(c *html.Node) bool { return hasClass(c, class) }); found != nil {
+        return text(found)
+    }
+    return ""
+}
+
+// WriteLeague writes a league in the typed data format. The file is only
+// replaced when the new contents load without validation errors, so a
+// changed page layout never reaches the API.
+func WriteLeague(path string, l *league.League) error {
+    content, err := json.MarshalIndent(l, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".scrape-*.json")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := tmp.Write(append(content, '\n')); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+
+    if _, _, err := league.LoadFile(tmp.Name(), l.ID); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
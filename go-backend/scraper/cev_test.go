@@ -0,0 +1,141 @@
+package scraper
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "go-backend/league"
+)
+
+func parseCEVFile(t *testing.T, id, path string) *league.League {
+    t.Helper()
+    f, err := os.Open(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+
+    l, err := ParseCEV(id, f)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return l
+}
+
+func TestParseCEVPools(t *testing.T) {
+    l := parseCEVFile(t, "cev-cl", "testdata/cev-cl.html")
+    if len(l.Fixture) != 6 {
+        t.Fatalf("%d fixtures, want 6", len(l.Fixture))
+    }
+
+    for i, round := range []string{"4th Round | Leg 1", "4th Round | Leg 1", "4th Round | Leg 2", "4th Round | Leg 2", "4th Round | Leg 3", "4th Round | Leg 3"} {
+        f := l.Fixture[i]
+        if f.Round != round || f.GroupName != "Pool A" {
+            t.Errorf("fixture %d: round %q in %q, want %q in Pool A", i, f.Round, f.GroupName, round)
+        }
+    }
+    first := l.Fixture[0]
+    if first.Date != "2025-11-27" || first.MatchTime != "19:00" || first.MatchNo != "CLW 41" || first.Score() != "3-0" || first.SetResults != "(25-18) (25-21) (25-16)" {
+        t.Errorf("first fixture: %+v", first)
+    }
+    if l.Fixture[4].IsPlayed || l.Fixture[4].HomeScore != nil {
+        t.Errorf("unplayed fixture has a result: %+v", l.Fixture[4])
+    }
+    if l.Stage != "4th Round" {
+        t.Errorf("stage = %q, want 4th Round", l.Stage)
+    }
+
+    if len(l.Teams) != 4 {
+        t.Fatalf("%d teams, want 4: %+v", len(l.Teams), l.Teams)
+    }
+    for _, team := range l.Teams {
+        if team.GroupName != "Pool A" || len(team.Country) != 3 {
+            t.Errorf("team %s: group %q, country %q", team.Name, team.GroupName, team.Country)
+        }
+    }
+}
+
+func TestParseCEVLegsAndGoldenSet(t *testing.T) {
+    l := parseCEVFile(t, "cev-cup", "testdata/cev-cup.html")
+    // Four 16th Finals legs, the golden set after the fourth, three 8th Finals legs
+    if len(l.Fixture) != 8 {
+        t.Fatalf("%d fixtures, want 8", len(l.Fixture))
+    }
+
+    for i, leg := range []int{1, 2, 1, 2, 2, 1, 2, 1} {
+        if f := l.Fixture[i]; f.Leg != leg {
+            t.Errorf("fixture %d (%s): leg %d, want %d", i, f.MatchNo, f.Leg, leg)
+        }
+    }
+
+    golden := l.Fixture[4]
+    if !golden.GoldenSet || golden.Round != "16th Finals" || golden.MatchNo != "CCW 20 GS" {
+        t.Fatalf("fixture after leg 2 is not its golden set: %+v", golden)
+    }
+    // Avarca de MENORCA hosted the leg and lost the golden set 13-15
+    if golden.HomeTeam != "Avarca de MENORCA" || golden.Score() != "0-1" || golden.SetResults != "(13-15)" || !golden.IsPlayed {
+        t.Errorf("golden set: %s - %s %s %s", golden.HomeTeam, golden.AwayTeam, golden.Score(), golden.SetResults)
+    }
+    if leg := l.Fixture[3]; leg.GoldenSet || leg.Score() != "3-1" {
+        t.Errorf("leg 2 carries the golden set: %+v", leg)
+    }
+    if l.Stage != "8th Finals" {
+        t.Errorf("stage = %q, want 8th Finals", l.Stage)
+    }
+
+    // The bracket placeholder is an opponent, not a team
+    if l.Fixture[7].AwayTeam != "Winner CCW 21/22" {
+        t.Errorf("placeholder fixture: %+v", l.Fixture[7])
+    }
+    for _, team := range l.Teams {
+        if strings.HasPrefix(team.Name, "Winner ") {
+            t.Errorf("placeholder %q in teams", team.Name)
+        }
+    }
+    if len(l.Teams) != 5 {
+        t.Errorf("%d teams, want 5: %+v", len(l.Teams), l.Teams)
+    }
+}
+
+func TestParseCEVInProgress(t *testing.T) {
+    page := `<h2>Final</h2><div class="c-match-summary">
+        <div class="c-match-summary__info">01/03/2026 18:00 - Match CLW 99</div>
+        <div class="c-match-summary__teamSummary"><span class="c-match-summary__teamName">A</span></div>
+        <div class="c-match-summary__score">2 - 1</div>
+        <div class="c-match-summary__teamSummary"><span class="c-match-summary__teamName">B</span></div>
+        <div class="c-match-summary__sets">25-20, 20-25, 25-22</div>
+    </div>`
+    l, err := ParseCEV("cev-cl", strings.NewReader(page))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if f := l.Fixture[0]; f.IsPlayed || f.HomeScore != nil || f.SetResults != "" {
+        t.Errorf("a 2-1 score in progress was taken as a result: %+v", f)
+    }
+}
+
+func TestWriteLeague(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "cev-cup.json")
+    l := parseCEVFile(t, "cev-cup", "testdata/cev-cup.html")
+    if err := WriteLeague(path, l); err != nil {
+        t.Fatal(err)
+    }
+    before, _ := os.ReadFile(path)
+
+    // A page that lost its team names gives a league without teams
+    broken := &league.League{ID: "cev-cup", Fixture: l.Fixture[:1]}
+    err := WriteLeague(path, broken)
+    if !errors.Is(err, league.ErrInvalid) {
+        t.Fatalf("writing a league without teams: %v, want ErrInvalid", err)
+    }
+    if after, _ := os.ReadFile(path); string(after) != string(before) {
+        t.Error("the invalid league replaced the file")
+    }
+    if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+        t.Errorf("temporary files left behind: %v", entries)
+    }
+}
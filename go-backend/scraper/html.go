@@ -0,0 +1,95 @@
+package scraper
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+
+    "golang.org/x/net/html"
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// fetch downloads a page with a browser user agent, the federation sites
+// refuse the default one. The client timeout still applies when ctx has none.
+func fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode != http.StatusOK {
+        resp.Body.Close()
+        return nil, fmt.Errorf("%s: %s", url, resp.Status)
+    }
+    return resp.Body, nil
+}
+
+func attr(n *html.Node, name string) string {
+    for _, a := range n.Attr {
+        if a.Key == name { return a.Val }
+    }
+    return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+    for _, c := range strings.Fields(attr(n, "class")) {
+        if c == class { return true }
+    }
+    return false
+}
+
+// text is the text content of a node and its children
+func text(n *html.Node) string {
+    var b strings.Builder
+    var walk func(n *html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.TextNode { b.WriteString(n.Data) }
+        for c := n.FirstChild; c != nil; c = c.NextSibling {
+            walk(c)
+        }
+    }
+    walk(n)
+    return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// find returns the first element below n that matches
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+    for c := n.FirstChild; c != nil; c = c.NextSibling {
+        if c.Type == html.ElementNode && match(c) { return c }
+        if found := find(c, match); found != nil { return found }
+    }
+    return nil
+}
+
+// findAll returns every element below n that matches, in document order
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+    var found []*html.Node
+    for c := n.FirstChild; c != nil; c = c.NextSibling {
+        if c.Type == html.ElementNode && match(c) { found = append(found, c) }
+        found = append(found, findAll(c, match)...)
+    }
+    return found
+}
+
+// spanText is the text of the first span whose id contains one of the
+// markers, the ASP.NET ids look like ..._gvMaclar_gevsahibi_0
+func spanText(row *html.Node, markers ...string) string {
+    span := find(row, func(n *html.Node) bool {
+        if n.Data != "span" { return false }
+        id := attr(n, "id")
+        for _, m := range markers {
+            if strings.Contains(id, m) { return true }
+        }
+        return false
+    })
+    if span == nil { return "" }
+    return text(span)
+}
@@ -0,0 +1,53 @@
+package scraper
+
+import (
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+// Match is a scraped result matched to a fixture of its league file
+type Match struct {
+    Result
+    Fixture league.Fixture
+}
+
+// MatchID is the id predictions use for the fixture, "home-away" with the
+// names of the league file
+func (m Match) MatchID() string {
+    return m.Fixture.HomeTeam + "-" + m.Fixture.AwayTeam
+}
+
+// Resolve matches results to the fixtures of leagues by team id, so names
+// spelled differently on the page still find their fixture through the team
+// registry. When a pairing is scheduled more than once (playoffs), the
+// fixture on the result's date wins, else the first unplayed one.
+func Resolve(results []Result, leagues map[string]*league.League) (matched []Match, unmatched []Result) {
+    for _, res := range results {
+        l, ok := leagues[res.League]
+        if res.League == "" || !ok {
+            unmatched = append(unmatched, res)
+            continue
+        }
+
+        scope := league.TeamScope(l.ID)
+        home := utils.ResolveTeam(scope, res.HomeTeam)
+        away := utils.ResolveTeam(scope, res.AwayTeam)
+
+        best := -1
+        for i, f := range l.Fixture {
+            if f.HomeTeamID != home || f.AwayTeamID != away || f.GoldenSet { continue }
+            if best < 0 { best = i }
+            if res.Date != "" && f.Date == res.Date {
+                best = i
+                break
+            }
+            if !f.IsPlayed && l.Fixture[best].IsPlayed { best = i }
+        }
+        if best < 0 {
+            unmatched = append(unmatched, res)
+            continue
+        }
+        matched = append(matched, Match{res, l.Fixture[best]})
+    }
+    return matched, unmatched
+}
@@ -0,0 +1,141 @@
+package scraper
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+
+    "golang.org/x/net/html"
+
+    "go-backend/utils"
+)
+
+// TVFTakvimURL is the federation's calendar page, every league's matches of
+// the current days grouped under league headers
+const TVFTakvimURL = "https://fikstur.tvf.org.tr/Takvim"
+
+// Result is one match row of a results page
+type Result struct {
+    League     string // our league id, "" for competitions we do not track
+    Header     string // the league header as on the page
+    HomeTeam   string
+    AwayTeam   string
+    HomeScore  int
+    AwayScore  int
+    Date       string // YYYY-MM-DD, "" when the page has none
+    SetResults string // "(25-20) (25-18) ..." as on the page
+}
+
+// Score is the result as "3-1"
+func (r Result) Score() string {
+    return fmt.Sprintf("%d-%d", r.HomeScore, r.AwayScore)
+}
+
+// Finished tells a result from the score so far of a match in progress
+func (r Result) Finished() bool {
+    return finished(r.HomeScore, r.AwayScore)
+}
+
+// finished is whether a set score is a best-of-five result, one side at
+// three sets and the other below
+func finished(home, away int) bool {
+    return utils.CheckResult(home, away) == nil
+}
+
+// FetchTVF downloads and parses the TVF calendar page
+func FetchTVF(ctx context.Context, url string) ([]Result, error) {
+    body, err := fetch(ctx, url)
+    if err != nil {
+        return nil, err
+    }
+    defer body.Close()
+    return ParseTVF(body)
+}
+
+// TVFLeague maps a TVF league header to a league id. Headers carry sponsor
+// names that change between seasons, so only the distinctive part is matched.
+// The page lists the men's leagues too, and clubs field men's and women's
+// teams under one name, so 1. and 2. Lig only count with KADINLAR.
+func TVFLeague(header string) string {
+    key := utils.TeamKey(header)
+    women := strings.Contains(key, "KADINLAR")
+    switch {
+    case strings.Contains(key, "SULTANLAR"):
+        return "vsl"
+    case women && strings.Contains(key, "1 LIG"):
+        return "1lig"
+    case women && strings.Contains(key, "2 LIG"):
+        return "2lig"
+    }
+    return ""
+}
+
+// ParseTVF reads the played matches of a TVF calendar page. Rows belong to
+// the nearest league header before them, whether the match table follows
+// the header directly or sits in a wrapper. Unplayed rows have empty score
+// spans and are left out; rows of leagues we do not track are kept with an
+// empty League so callers can report them. A match in progress shows its
+// score so far, Result.Finished tells it from a result.
+func ParseTVF(r io.Reader) ([]Result, error) {
+    doc, err := html.Parse(r)
+    if err != nil {
+        return nil, err
+    }
+
+    var results []Result
+    header := ""
+    var walk func(n *html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.ElementNode {
+            if hasClass(n, "tvf-fixture-league-header") {
+                header = strings.TrimSpace(text(n))
+                return
+            }
+            if n.Data == "tr" {
+                if res, ok := parseTVFRow(n); ok {
+                    res.Header, res.League = header, TVFLeague(header)
+                    results = append(results, res)
+                }
+                return
+            }
+        }
+        for c := n.FirstChild; c != nil; c = c.NextSibling {
+            walk(c)
+        }
+    }
+    walk(doc)
+    return results, nil
+}
+
+// parseTVFRow reads the result spans of a match row, the away side is
+// "misafir" or "deplasman" depending on the page
+func parseTVFRow(row *html.Node) (Result, bool) {
+    home := spanText(row, "_gevsahibi")
+    away := spanText(row, "_gmisafir", "_gdeplasman")
+    homeScore, err1 := strconv.Atoi(spanText(row, "_gseta"))
+    awayScore, err2 := strconv.Atoi(spanText(row, "_gsetb", "_gsetd"))
+    if home == "" || away == "" || err1 != nil || err2 != nil {
+        return Result{}, false
+    }
+
+    return Result{
+        HomeTeam:   home,
+        AwayTeam:   away,
+        HomeScore:  homeScore,
+        AwayScore:  awayScore,
+        Date:       isoDate(spanText(row, "_gtarih")),
+        SetResults: spanText(row, "_gsetsonuclari"),
+    }, true
+}
+
+// isoDate converts the pages' DD.MM.YYYY dates
+func isoDate(s string) string {
+    t, err := time.Parse("02.01.2006", s)
+    if err != nil {
+        return ""
+    }
+    return t.Format("2006-01-02")
+}
@@ -0,0 +1,133 @@
+package scraper
+
+import (
+    "os"
+    "testing"
+
+    "go-backend/league"
+    "go-backend/utils"
+)
+
+func parseTVFFile(t *testing.T) []Result {
+    t.Helper()
+    f, err := os.Open("testdata/tvf-takvim.html")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+
+    results, err := ParseTVF(f)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return results
+}
+
+func TestParseTVF(t *testing.T) {
+    results := parseTVFFile(t)
+
+    // The unplayed AYDIN - GALATASARAY row has empty score spans
+    want := []struct {
+        league, home, away, score string
+    }{
+        {"vsl", "KUZEYBORU", "ECZACIBAŞI DYNAVİT", "1-3"},
+        {"vsl", "İLBANK", "BEŞİKTAŞ", "3-2"},
+        {"", "HALKBANK", "ZİRAAT BANKKART", "3-0"},
+        {"1lig", "İBB SPOR KULÜBÜ", "EDREMİT BLD. ALTINOLUK", "3-0"},
+        {"1lig", "VESTEL MANİSA B.ŞEHİR BLD.", "İZMİRSPOR", "2-3"},
+        {"1lig", "EDREMİT BLD. ALTINOLUK", "VAKIFBANK", "1-0"},
+        {"2lig", "BİZİMKENT VOLEYBOL", "BEYLİKDÜZÜ BEYKENT", "0-3"},
+        {"2lig", "SMAÇ SPOR", "ÇATALCA BLD.", "3-1"},
+        {"2lig", "HAYALİ SPOR", "ÇATALCA BLD.", "3-0"},
+    }
+    if len(results) != len(want) {
+        t.Fatalf("%d results, want %d: %+v", len(results), len(want), results)
+    }
+    for i, w := range want {
+        r := results[i]
+        if r.League != w.league || r.HomeTeam != w.home || r.AwayTeam != w.away || r.Score() != w.score {
+            t.Errorf("row %d: %s %s - %s %s, want %s %s - %s %s", i, r.League, r.HomeTeam, r.AwayTeam, r.Score(), w.league, w.home, w.away, w.score)
+        }
+    }
+
+    // Rows in a wrapper div still take the header before it
+    if results[3].Header != "ARABICA COFFEE HOUSE KADINLAR 1. LİG" {
+        t.Errorf("1. Lig header = %q", results[3].Header)
+    }
+    // The 2. Lig table names its away columns _gsetd and _gdeplasman
+    if r := results[6]; r.AwayScore != 3 || r.Date != "2026-01-04" || r.SetResults != "(20-25) (22-25) (19-25)" {
+        t.Errorf("_gsetd row: %+v", r)
+    }
+}
+
+func TestTVFLeague(t *testing.T) {
+    for header, want := range map[string]string{
+        "Vodafone Sultanlar Ligi":              "vsl",
+        "ARABICA COFFEE HOUSE KADINLAR 1. LİG": "1lig",
+        "KADINLAR 2. LİG":                      "2lig",
+        "AXA SİGORTA EFELER LİGİ":              "",
+        "ERKEKLER 1. LİG":                      "",
+        "ERKEKLER 2. LİG":                      "",
+        "":                                     "",
+    } {
+        if got := TVFLeague(header); got != want {
+            t.Errorf("TVFLeague(%q) = %q, want %q", header, got, want)
+        }
+    }
+}
+
+// fixture is a match of a test league with the team ids the data files get
+// from the registry
+func fixture(leagueID, date, home, away string) league.Fixture {
+    return league.Fixture{
+        Date:       date,
+        HomeTeam:   home,
+        AwayTeam:   away,
+        HomeTeamID: utils.ResolveTeam(leagueID, home),
+        AwayTeamID: utils.ResolveTeam(leagueID, away),
+    }
+}
+
+func TestResolve(t *testing.T) {
+    leagues := map[string]*league.League{
+        "vsl": {ID: "vsl", Fixture: []league.Fixture{
+            fixture("vsl", "2026-01-03", "Kuzeyboru", "Eczacıbaşı Dynavit"),
+            fixture("vsl", "2026-01-03", "İlbank", "Beşiktaş"),
+        }},
+        "1lig": {ID: "1lig", Fixture: []league.Fixture{
+            fixture("1lig", "2026-01-04", "İBB Spor Kulübü", "Edremit Bld. Altınoluk"),
+            fixture("1lig", "2026-01-04", "Vestel Manisa B.Şehir Bld.", "İzmirspor"),
+            fixture("1lig", "2026-01-04", "Edremit Bld. Altınoluk", "Vakıfbank"),
+        }},
+        "2lig": {ID: "2lig", Fixture: []league.Fixture{
+            fixture("2lig", "2026-01-04", "Bizimkent Voleybol", "Beylikdüzü Beykent"),
+            // Scheduled twice, the date of the row picks the fixture
+            fixture("2lig", "2025-11-02", "Smaç Spor", "Çatalca Bld."),
+            fixture("2lig", "2026-01-04", "Smaç Spor", "Çatalca Bld."),
+        }},
+    }
+
+    matched, unmatched := Resolve(parseTVFFile(t), leagues)
+    if len(matched) != 7 {
+        t.Fatalf("%d matched, want 7: %+v", len(matched), matched)
+    }
+    if m := matched[0]; m.MatchID() != "Kuzeyboru-Eczacıbaşı Dynavit" {
+        t.Errorf("match id %q, want the names of the league file", m.MatchID())
+    }
+    if m := matched[6]; m.Fixture.Date != "2026-01-04" {
+        t.Errorf("SMAÇ - ÇATALCA resolved to the fixture of %s", m.Fixture.Date)
+    }
+
+    // The 1-0 row is a match in progress: it finds its fixture but is no result
+    for _, m := range matched {
+        live := m.HomeTeam == "EDREMİT BLD. ALTINOLUK" && m.AwayTeam == "VAKIFBANK"
+        if m.Finished() == live {
+            t.Errorf("%s %s finished = %t", m.MatchID(), m.Score(), m.Finished())
+        }
+    }
+
+    // Efeler Ligi is not tracked, HAYALİ SPOR has no fixture
+    if len(unmatched) != 2 || unmatched[0].League != "" || unmatched[1].League != "2lig" || unmatched[1].HomeTeam != "HAYALİ SPOR" {
+        t.Errorf("unmatched = %+v", unmatched)
+    }
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+    "fmt"
     "math"
     "sort"
     "strconv"
@@ -8,6 +9,7 @@ import (
 )
 
 type TeamStats struct {
+    ID       string `json:"id,omitempty"` // stable team id, see TeamRegistry
     Name     string `json:"name"`
     Points   int    `json:"points"`
     Wins     int    `json:"wins"`
@@ -22,70 +24,183 @@ type Match struct {
     ResultScore string `json:"resultScore"`
     IsPlayed    bool   `json:"isPlayed"`
     MatchDate   string `json:"matchDate"`
+    HomeTeamID  string `json:"homeTeamId,omitempty"`
+    AwayTeamID  string `json:"awayTeamId,omitempty"`
 }
 
-func CalculateElo(teams []TeamStats, matches []Match) map[string]float64 {
-    ratings := make(map[string]float64)
-    
-    // Initialize
-    for _, t := range teams {
-        ratings[t.Name] = 1200.0
+// Ratings are keyed by team id when it is known and by name otherwise
+
+func (t TeamStats) Key() string {
+    if t.ID != "" { return t.ID }
+    return t.Name
+}
+
+func (m Match) HomeKey() string {
+    if m.HomeTeamID != "" { return m.HomeTeamID }
+    return m.HomeTeam
+}
+
+func (m Match) AwayKey() string {
+    if m.AwayTeamID != "" { return m.AwayTeamID }
+    return m.AwayTeam
+}
+
+const InitialElo = 1200.0
+
+// ParseScore splits a "3-1" style result into home and away sets.
+func ParseScore(score string) (int, int, bool) {
+    parts := strings.Split(score, "-")
+    if len(parts) != 2 { return 0, 0, false }
+
+    hSets, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+    aSets, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+    if err1 != nil || err2 != nil { return 0, 0, false }
+
+    return hSets, aSets, true
+}
+
+// MatchPoints gives the league points for a result: 3-0 for a 3-0 or 3-1
+// win, 2-1 for a 3-2 win.
+func MatchPoints(hSets, aSets int) (int, int) {
+    switch {
+    case hSets == 3 && aSets <= 1:
+        return 3, 0
+    case hSets == 3:
+        return 2, 1
+    case aSets == 3 && hSets <= 1:
+        return 0, 3
+    case aSets == 3:
+        return 1, 2
     }
+    return 0, 0
+}
 
-    // Sort valid matches by date
-    // Making a copy to sort
+// playedMatches returns the played matches with a result, sorted by date
+func playedMatches(matches []Match) []Match {
     validMatches := make([]Match, 0)
     for _, m := range matches {
         if m.IsPlayed && m.ResultScore != "" {
             validMatches = append(validMatches, m)
         }
     }
-    
-    // Sort
-    sort.Slice(validMatches, func(i, j int) bool {
+
+    sort.SliceStable(validMatches, func(i, j int) bool {
         return validMatches[i].MatchDate < validMatches[j].MatchDate
     })
+    return validMatches
+}
+
+// CalculateElo rates the teams with the default parameters
+func CalculateElo(teams []TeamStats, matches []Match) map[string]float64 {
+    return CalculateEloWithParams(teams, matches, EloParamsFor(""))
+}
+
+func CalculateEloWithParams(teams []TeamStats, matches []Match, params EloParams) map[string]float64 {
+    return calculateElo(initialRatings(teams), matches, params, nil)
+}
+
+// RatingPoint is a team's rating change from one match
+type RatingPoint struct {
+    Date         string  `json:"date"`
+    Opponent     string  `json:"opponent"`
+    OpponentID   string  `json:"opponentId,omitempty"`
+    Home         bool    `json:"home"`
+    Result       string  `json:"result"` // sets from the team's point of view, e.g. "3-1"
+    Won          bool    `json:"won"`
+    RatingBefore float64 `json:"ratingBefore"`
+    RatingAfter  float64 `json:"ratingAfter"`
+    ExpectedWin  float64 `json:"expectedWin"`
+}
+
+// CalculateEloHistory also returns every team's rating after each processed match
+func CalculateEloHistory(teams []TeamStats, matches []Match, params EloParams) (map[string]float64, map[string][]RatingPoint) {
+    history := make(map[string][]RatingPoint)
+    ratings := calculateElo(initialRatings(teams), matches, params, history)
+    return ratings, history
+}
+
+func initialRatings(teams []TeamStats) map[string]float64 {
+    ratings := make(map[string]float64)
+    for _, t := range teams {
+        ratings[t.Key()] = InitialElo
+    }
+    return ratings
+}
+
+// calculateElo updates the seeded ratings in place; history is only recorded when non-nil
+func calculateElo(ratings map[string]float64, matches []Match, params EloParams, history map[string][]RatingPoint) map[string]float64 {
+    for _, m := range playedMatches(matches) {
+        home, away := m.HomeKey(), m.AwayKey()
+
+        homeRatings := ratings[home]
+        if homeRatings == 0 { homeRatings = InitialElo }
+
+        awayRatings := ratings[away]
+        if awayRatings == 0 { awayRatings = InitialElo }
 
-    for _, m := range validMatches {
-        homeRatings := ratings[m.HomeTeam]
-        if homeRatings == 0 { homeRatings = 1200 }
-        
-        awayRatings := ratings[m.AwayTeam]
-        if awayRatings == 0 { awayRatings = 1200 }
-
-        parts := strings.Split(m.ResultScore, "-")
-        if len(parts) != 2 { continue }
-        
-        hSets, _ := strconv.Atoi(parts[0])
-        aSets, _ := strconv.Atoi(parts[1])
-
-        actualHome := 0.0
-        if hSets > aSets { actualHome = 1.0 }
-        
-        actualAway := 1.0 - actualHome
-        
-        // K-Factor
-        k := 32.0
-        
-        // Expected
-        expectedHome := 1.0 / (1.0 + math.Pow(10, (awayRatings - homeRatings)/400.0))
-        expectedAway := 1.0 / (1.0 + math.Pow(10, (homeRatings - awayRatings)/400.0))
-        
-        // Multiplier
-        multiplier := 1.0
-        diff := math.Abs(float64(hSets - aSets))
-        if diff == 3 {
-            multiplier = 1.3
-        } else if diff == 2 {
-            multiplier = 1.1
+        hSets, aSets, ok := ParseScore(m.ResultScore)
+        if !ok { continue }
+
+        newHome, newAway := params.Update(homeRatings, awayRatings, hSets, aSets)
+
+        ratings[home] = newHome
+        ratings[away] = newAway
+
+        if history != nil {
+            history[home] = append(history[home], RatingPoint{
+                Date:         m.MatchDate,
+                Opponent:     m.AwayTeam,
+                OpponentID:   m.AwayTeamID,
+                Home:         true,
+                Result:       fmt.Sprintf("%d-%d", hSets, aSets),
+                Won:          hSets > aSets,
+                RatingBefore: homeRatings,
+                RatingAfter:  newHome,
+                ExpectedWin:  params.Expected(homeRatings, awayRatings),
+            })
+            history[away] = append(history[away], RatingPoint{
+                Date:         m.MatchDate,
+                Opponent:     m.HomeTeam,
+                OpponentID:   m.HomeTeamID,
+                Home:         false,
+                Result:       fmt.Sprintf("%d-%d", aSets, hSets),
+                Won:          aSets > hSets,
+                RatingBefore: awayRatings,
+                RatingAfter:  newAway,
+                ExpectedWin:  params.Expected(awayRatings, homeRatings),
+            })
         }
-        
-        newHome := homeRatings + k * multiplier * (actualHome - expectedHome)
-        newAway := awayRatings + k * multiplier * (actualAway - expectedAway)
-        
-        ratings[m.HomeTeam] = newHome
-        ratings[m.AwayTeam] = newAway
     }
 
     return ratings
 }
+
+// Expected returns the win probability of a team rated home against away
+func (p EloParams) Expected(home, away float64) float64 {
+    return 1.0 / (1.0 + math.Pow(10, (away-home)/p.Scale))
+}
+
+// Update applies one match result and returns the new ratings
+func (p EloParams) Update(home, away float64, hSets, aSets int) (float64, float64) {
+    actualHome := 0.0
+    if hSets > aSets { actualHome = 1.0 }
+
+    actualAway := 1.0 - actualHome
+
+    // Expected
+    expectedHome := p.Expected(home, away)
+    expectedAway := p.Expected(away, home)
+
+    // Multiplier
+    multiplier := 1.0
+    diff := math.Abs(float64(hSets - aSets))
+    if diff == 3 {
+        multiplier = p.SweepMultiplier
+    } else if diff == 2 {
+        multiplier = p.MarginMultiplier
+    }
+
+    newHome := home + p.K * multiplier * (actualHome - expectedHome)
+    newAway := away + p.K * multiplier * (actualAway - expectedAway)
+    return newHome, newAway
+}
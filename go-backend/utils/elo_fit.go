@@ -0,0 +1,205 @@
+package utils
+
+import (
+    "math"
+)
+
+// EloLogLoss replays the matches in date order and returns the mean log-loss
+// of the pre-match home win probabilities.
+func EloLogLoss(matches []Match, params EloParams) (float64, int) {
+    return eloLogLoss(playedMatches(matches), params)
+}
+
+func eloLogLoss(played []Match, params EloParams) (float64, int) {
+    return eloLogLossFrom(played, params, 0)
+}
+
+// eloLogLossFrom replays every match but only scores played[from:]
+func eloLogLossFrom(played []Match, params EloParams, from int) (float64, int) {
+    ratings := make(map[string]float64)
+    rating := func(name string) float64 {
+        if r, ok := ratings[name]; ok { return r }
+        return InitialElo
+    }
+
+    total := 0.0
+    count := 0
+    for i, m := range played {
+        hSets, aSets, ok := ParseScore(m.ResultScore)
+        if !ok || hSets == aSets { continue }
+
+        home := rating(m.HomeKey())
+        away := rating(m.AwayKey())
+
+        if i >= from {
+            p := math.Min(math.Max(params.Expected(home, away), 1e-9), 1-1e-9)
+            if hSets > aSets {
+                total -= math.Log(p)
+            } else {
+                total -= math.Log(1 - p)
+            }
+            count++
+        }
+
+        ratings[m.HomeKey()], ratings[m.AwayKey()] = params.Update(home, away, hSets, aSets)
+    }
+
+    if count == 0 { return 0, 0 }
+    return total / float64(count), count
+}
+
+// EloGrid is the search space of FitEloParams
+type EloGrid struct {
+    K                []float64
+    SweepMultiplier  []float64
+    MarginMultiplier []float64
+    Scale            []float64
+}
+
+func steps(from, to, step float64) []float64 {
+    values := make([]float64, 0)
+    for v := from; v <= to+step/2; v += step {
+        values = append(values, math.Round(v*1000)/1000)
+    }
+    return values
+}
+
+// Every team starts at the same rating, so rating gaps grow with K and the
+// expected score only depends on K/Scale. The default grid keeps the scale at
+// 400 and fits K instead. The bounds are wide on purpose, a fit that still
+// ends up on one of them is reported by AtBounds.
+var DefaultEloGrid = EloGrid{
+    K:                steps(8, 320, 8),
+    SweepMultiplier:  steps(1.0, 8.0, 0.25),
+    MarginMultiplier: steps(1.0, 6.0, 0.2),
+    Scale:            []float64{400},
+}
+
+// AtBounds lists the parameters the grid allowed to vary that sit on one of
+// its edges, where the real optimum may lie outside the search space
+func (g EloGrid) AtBounds(p EloParams) []string {
+    var names []string
+    check := func(name string, values []float64, v float64) {
+        lo, hi := span(values)
+        if lo == hi { return }
+        if step := (hi - lo) / float64(len(values)-1); v <= lo+step/2 || v >= hi-step/2 {
+            names = append(names, name)
+        }
+    }
+    check("k", g.K, p.K)
+    check("sweepMultiplier", g.SweepMultiplier, p.SweepMultiplier)
+    check("marginMultiplier", g.MarginMultiplier, p.MarginMultiplier)
+    check("scale", g.Scale, p.Scale)
+    return names
+}
+
+// EloFit is a fit on the start of the season scored on the rest of it
+type EloFit struct {
+    Params          EloParams
+    LogLoss         float64 // on the fitted matches
+    Baseline        float64 // the same with the default parameters
+    Matches         int
+    HoldoutLogLoss  float64 // on the held-out tail, after replaying the fitted matches
+    HoldoutBaseline float64 // the same with the default parameters
+    HoldoutMatches  int
+}
+
+// FitEloHoldout fits the parameters on all but the last holdout fraction of
+// the played matches (by date) and scores the result on that tail
+func FitEloHoldout(matches []Match, grid EloGrid, holdout float64) EloFit {
+    played := playedMatches(matches)
+    cut := len(played) - int(float64(len(played))*holdout)
+
+    var fit EloFit
+    fit.Params, fit.LogLoss = FitEloParams(played[:cut], grid)
+    fit.Baseline, fit.Matches = eloLogLoss(played[:cut], DefaultEloParams)
+    fit.HoldoutLogLoss, fit.HoldoutMatches = eloLogLossFrom(played, fit.Params, cut)
+    fit.HoldoutBaseline, _ = eloLogLossFrom(played, DefaultEloParams, cut)
+    return fit
+}
+
+// FitEloParams runs a grid search followed by a coordinate pattern search
+// inside the grid bounds and returns the parameters with the lowest log-loss.
+func FitEloParams(matches []Match, grid EloGrid) (EloParams, float64) {
+    played := playedMatches(matches)
+    lower, upper := grid.bounds()
+
+    best := DefaultEloParams
+    bestLoss, _ := eloLogLoss(played, best)
+
+    for _, k := range grid.K {
+        for _, sweep := range grid.SweepMultiplier {
+            for _, margin := range grid.MarginMultiplier {
+                if margin > sweep { continue } // a 3-1 never counts more than a 3-0
+                for _, scale := range grid.Scale {
+                    p := EloParams{K: k, SweepMultiplier: sweep, MarginMultiplier: margin, Scale: scale}
+                    if loss, _ := eloLogLoss(played, p); loss < bestLoss {
+                        best, bestLoss = p, loss
+                    }
+                }
+            }
+        }
+    }
+
+    // Refine around the grid optimum, halving the step until it is negligible
+    step := []float64{2, 0.05, 0.025, 25}
+    for round := 0; round < 6; round++ {
+        improved := true
+        for improved {
+            improved = false
+            for dim := range step {
+                for _, dir := range []float64{-1, 1} {
+                    p := best
+                    switch dim {
+                    case 0: p.K += dir * step[dim]
+                    case 1: p.SweepMultiplier += dir * step[dim]
+                    case 2: p.MarginMultiplier += dir * step[dim]
+                    case 3: p.Scale += dir * step[dim]
+                    }
+                    if !p.within(lower, upper) || p.MarginMultiplier > p.SweepMultiplier { continue }
+
+                    if loss, _ := eloLogLoss(played, p); loss < bestLoss {
+                        best, bestLoss = p, loss
+                        improved = true
+                    }
+                }
+            }
+        }
+        for dim := range step {
+            step[dim] /= 2
+        }
+    }
+
+    best.K = math.Round(best.K*100) / 100
+    best.SweepMultiplier = math.Round(best.SweepMultiplier*1000) / 1000
+    best.MarginMultiplier = math.Round(best.MarginMultiplier*1000) / 1000
+    best.Scale = math.Round(best.Scale*10) / 10
+    bestLoss, _ = eloLogLoss(played, best)
+
+    return best, bestLoss
+}
+
+func span(values []float64) (float64, float64) {
+    lo, hi := math.Inf(1), math.Inf(-1)
+    for _, v := range values {
+        lo = math.Min(lo, v)
+        hi = math.Max(hi, v)
+    }
+    return lo, hi
+}
+
+func (g EloGrid) bounds() (EloParams, EloParams) {
+    var lower, upper EloParams
+    lower.K, upper.K = span(g.K)
+    lower.SweepMultiplier, upper.SweepMultiplier = span(g.SweepMultiplier)
+    lower.MarginMultiplier, upper.MarginMultiplier = span(g.MarginMultiplier)
+    lower.Scale, upper.Scale = span(g.Scale)
+    return lower, upper
+}
+
+func (p EloParams) within(lower, upper EloParams) bool {
+    return p.K >= lower.K && p.K <= upper.K &&
+        p.SweepMultiplier >= lower.SweepMultiplier && p.SweepMultiplier <= upper.SweepMultiplier &&
+        p.MarginMultiplier >= lower.MarginMultiplier && p.MarginMultiplier <= upper.MarginMultiplier &&
+        p.Scale >= lower.Scale && p.Scale <= upper.Scale
+}
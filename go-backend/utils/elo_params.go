@@ -0,0 +1,89 @@
+package utils
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+)
+
+// EloParams are the tunable parts of the rating model.
+type EloParams struct {
+    K                float64 `json:"k"`
+    SweepMultiplier  float64 `json:"sweepMultiplier"`  // 3-0 results
+    MarginMultiplier float64 `json:"marginMultiplier"` // 3-1 results
+    Scale            float64 `json:"scale"`
+}
+
+var DefaultEloParams = EloParams{
+    K:                32,
+    SweepMultiplier:  1.3,
+    MarginMultiplier: 1.1,
+    Scale:            400,
+}
+
+// FittedEloParams is one league's entry in the params file, with the fit quality
+type FittedEloParams struct {
+    EloParams
+    LogLoss         float64 `json:"logLoss"`
+    BaselineLogLoss float64 `json:"baselineLogLoss"`
+    Matches         int     `json:"matches"`
+    // The held-out end of the season, scored with parameters fitted without
+    // it. The saved parameters are refit on every match.
+    HoldoutLogLoss         float64 `json:"holdoutLogLoss,omitempty"`
+    HoldoutBaselineLogLoss float64 `json:"holdoutBaselineLogLoss,omitempty"`
+    HoldoutMatches         int     `json:"holdoutMatches,omitempty"`
+}
+
+type EloParamsFile struct {
+    GeneratedAt string                     `json:"generatedAt"`
+    Default     EloParams                  `json:"default"`
+    Leagues     map[string]FittedEloParams `json:"leagues"`
+}
+
+const DefaultEloParamsPath = "config/elo-params.json"
+
+// Loaded at startup, read-only afterwards
+var eloParamsFile = EloParamsFile{Default: DefaultEloParams}
+
+// LoadEloParams reads the fitted parameters written by the fit-elo command
+func LoadEloParams(path string) error {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+
+    var file EloParamsFile
+    if err := json.Unmarshal(content, &file); err != nil {
+        return err
+    }
+    if !file.Default.valid() {
+        file.Default = DefaultEloParams
+    }
+
+    eloParamsFile = file
+    return nil
+}
+
+func SaveEloParams(path string, file EloParamsFile) error {
+    content, err := json.MarshalIndent(file, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// EloParamsFor returns the fitted parameters of a league id, as fit-elo
+// keys them, or the defaults
+func EloParamsFor(league string) EloParams {
+    if p, ok := eloParamsFile.Leagues[league]; ok && p.valid() {
+        return p.EloParams
+    }
+    return eloParamsFile.Default
+}
+
+func (p EloParams) valid() bool {
+    return p.K > 0 && p.Scale > 0 && p.SweepMultiplier > 0 && p.MarginMultiplier > 0
+}
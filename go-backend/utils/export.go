@@ -0,0 +1,212 @@
+package utils
+
+import (
+    "archive/zip"
+    "encoding/csv"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// Table is a sheet of exported rows. Cells are strings, numbers, bools or
+// nil for empty.
+type Table struct {
+    Name    string
+    Columns []string
+    Rows    [][]interface{}
+}
+
+// TableFromStructs builds a table from a slice of structs, one column per
+// exported field named after its json tag. Pointers are dereferenced and
+// string slices joined; fields holding other slices, maps or structs are left out.
+func TableFromStructs(name string, rows interface{}) Table {
+    v := reflect.ValueOf(rows)
+    t := v.Type().Elem()
+    table := Table{Name: name}
+
+    var fields []int
+    for i := 0; i < t.NumField(); i++ {
+        f := t.Field(i)
+        if !f.IsExported() || !exportable(f.Type) { continue }
+        column := strings.Split(f.Tag.Get("json"), ",")[0]
+        if column == "-" { continue }
+        if column == "" { column = f.Name }
+        table.Columns = append(table.Columns, column)
+        fields = append(fields, i)
+    }
+
+    for i := 0; i < v.Len(); i++ {
+        row := make([]interface{}, 0, len(fields))
+        for _, field := range fields {
+            row = append(row, cellValue(v.Index(i).Field(field)))
+        }
+        table.Rows = append(table.Rows, row)
+    }
+    return table
+}
+
+func exportable(t reflect.Type) bool {
+    if t.Kind() == reflect.Ptr { t = t.Elem() }
+    switch t.Kind() {
+    case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+        return true
+    case reflect.Slice:
+        return t.Elem().Kind() == reflect.String
+    }
+    return false
+}
+
+func cellValue(v reflect.Value) interface{} {
+    if v.Kind() == reflect.Ptr {
+        if v.IsNil() { return nil }
+        v = v.Elem()
+    }
+    if v.Kind() == reflect.Slice {
+        parts := make([]string, v.Len())
+        for i := range parts {
+            parts[i] = v.Index(i).String()
+        }
+        return strings.Join(parts, " ")
+    }
+    return v.Interface()
+}
+
+func cellText(cell interface{}) string {
+    switch v := cell.(type) {
+    case nil:
+        return ""
+    case string:
+        return v
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64)
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// WriteCSV writes the table as UTF-8 CSV. The byte order mark makes Excel
+// read Turkish characters correctly.
+func WriteCSV(w io.Writer, t Table) error {
+    if _, err := io.WriteString(w, "\ufeff"); err != nil {
+        return err
+    }
+    cw := csv.NewWriter(w)
+    if err := cw.Write(t.Columns); err != nil {
+        return err
+    }
+    record := make([]string, len(t.Columns))
+    for _, row := range t.Rows {
+        for i := range record {
+            record[i] = ""
+            if i < len(row) { record[i] = cellText(row[i]) }
+        }
+        if err := cw.Write(record); err != nil {
+            return err
+        }
+    }
+    cw.Flush()
+    return cw.Error()
+}
+
+// columnName turns a zero-based column index into A, B, ..., Z, AA, ...
+func columnName(i int) string {
+    name := ""
+    for i++; i > 0; i = (i - 1) / 26 {
+        name = string(rune('A'+(i-1)%26)) + name
+    }
+    return name
+}
+
+func xmlText(s string) string {
+    var b strings.Builder
+    xml.EscapeText(&b, []byte(s))
+    return b.String()
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+// WriteXLSX writes the table as a single-sheet workbook. Strings are inline
+// so no shared string table is needed; numbers stay numeric.
+func WriteXLSX(w io.Writer, t Table) error {
+    zw := zip.NewWriter(w)
+
+    // Sheet names are at most 31 characters and can't hold []:*?/\
+    sheet := strings.Map(func(r rune) rune {
+        if strings.ContainsRune(`[]:*?/\`, r) { return '-' }
+        return r
+    }, t.Name)
+    if sheet == "" { sheet = "Sheet1" }
+    if runes := []rune(sheet); len(runes) > 31 { sheet = string(runes[:31]) }
+
+    parts := []struct{ name, content string }{
+        {"[Content_Types].xml", xlsxContentTypes},
+        {"_rels/.rels", xlsxRootRels},
+        {"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlText(sheet))},
+        {"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+    }
+    for _, p := range parts {
+        f, err := zw.Create(p.name)
+        if err != nil { return err }
+        if _, err := io.WriteString(f, p.content); err != nil { return err }
+    }
+
+    f, err := zw.Create("xl/worksheets/sheet1.xml")
+    if err != nil { return err }
+    io.WriteString(f, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+    io.WriteString(f, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+    writeRow := func(r int, cells []interface{}) {
+        fmt.Fprintf(f, `<row r="%d">`, r)
+        for i, cell := range cells {
+            ref := columnName(i) + strconv.Itoa(r)
+            switch v := cell.(type) {
+            case nil:
+            case int, int64, float64:
+                fmt.Fprintf(f, `<c r="%s"><v>%s</v></c>`, ref, cellText(v))
+            case bool:
+                b := 0
+                if v { b = 1 }
+                fmt.Fprintf(f, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+            default:
+                fmt.Fprintf(f, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlText(cellText(v)))
+            }
+        }
+        io.WriteString(f, `</row>`)
+    }
+
+    header := make([]interface{}, len(t.Columns))
+    for i, c := range t.Columns {
+        header[i] = c
+    }
+    writeRow(1, header)
+    for i, row := range t.Rows {
+        writeRow(i+2, row)
+    }
+    io.WriteString(f, `</sheetData></worksheet>`)
+
+    return zw.Close()
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+    "fmt"
+    "io"
+    "strings"
+    "time"
+)
+
+// CalendarEvent is one VEVENT of an iCalendar feed
+type CalendarEvent struct {
+    UID          string
+    Start        time.Time
+    AllDay       bool // Start is a date without a time
+    Duration     time.Duration
+    Summary      string
+    Description  string
+    Location     string
+    LastModified time.Time
+    Sequence     int // raised on every change, so calendar apps take the update
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// writeLine folds lines longer than 75 octets as RFC 5545 asks, without
+// splitting UTF-8 sequences
+func writeLine(w io.Writer, line string) {
+    limit := 75
+    for len(line) > limit {
+        cut := limit
+        for cut > 0 && line[cut]&0xC0 == 0x80 {
+            cut--
+        }
+        io.WriteString(w, line[:cut]+"\r\n ")
+        line = line[cut:]
+        limit = 74 // continuation lines start with a space
+    }
+    io.WriteString(w, line+"\r\n")
+}
+
+// writeTimezone writes the VTIMEZONE of a zone without daylight saving time,
+// as Turkey has been since 2016, with its current offset
+func writeTimezone(w io.Writer, loc *time.Location) {
+    name, offset := time.Now().In(loc).Zone()
+    sign := "+"
+    if offset < 0 {
+        sign, offset = "-", -offset
+    }
+    utcOffset := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+
+    writeLine(w, "BEGIN:VTIMEZONE")
+    writeLine(w, "TZID:"+loc.String())
+    writeLine(w, "BEGIN:STANDARD")
+    writeLine(w, "DTSTART:19700101T000000")
+    writeLine(w, "TZOFFSETFROM:"+utcOffset)
+    writeLine(w, "TZOFFSETTO:"+utcOffset)
+    writeLine(w, "TZNAME:"+name)
+    writeLine(w, "END:STANDARD")
+    writeLine(w, "END:VTIMEZONE")
+}
+
+// WriteCalendar writes events as an iCalendar feed. Timed events are local
+// times of loc, which the feed defines in a VTIMEZONE block.
+func WriteCalendar(w io.Writer, name string, loc *time.Location, events []CalendarEvent) {
+    const (
+        utc   = "20060102T150405Z"
+        local = "20060102T150405"
+    )
+    stamp := time.Now().UTC().Format(utc)
+    tzid := ";TZID=" + loc.String() + ":"
+
+    writeLine(w, "BEGIN:VCALENDAR")
+    writeLine(w, "VERSION:2.0")
+    writeLine(w, "PRODID:-//VolleySimulator//Fixtures//TR")
+    writeLine(w, "CALSCALE:GREGORIAN")
+    writeLine(w, "METHOD:PUBLISH")
+    writeLine(w, "X-WR-CALNAME:"+icalEscaper.Replace(name))
+    writeLine(w, "X-WR-TIMEZONE:"+loc.String())
+    writeTimezone(w, loc)
+
+    for _, e := range events {
+        writeLine(w, "BEGIN:VEVENT")
+        writeLine(w, "UID:"+e.UID)
+        writeLine(w, "DTSTAMP:"+stamp)
+        if e.AllDay {
+            writeLine(w, "DTSTART;VALUE=DATE:"+e.Start.Format("20060102"))
+            writeLine(w, "DTEND;VALUE=DATE:"+e.Start.AddDate(0, 0, 1).Format("20060102"))
+        } else {
+            writeLine(w, "DTSTART"+tzid+e.Start.In(loc).Format(local))
+            writeLine(w, "DTEND"+tzid+e.Start.Add(e.Duration).In(loc).Format(local))
+        }
+        writeLine(w, fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+        if !e.LastModified.IsZero() {
+            writeLine(w, "LAST-MODIFIED:"+e.LastModified.UTC().Format(utc))
+        }
+        writeLine(w, "SUMMARY:"+icalEscaper.Replace(e.Summary))
+        if e.Description != "" {
+            writeLine(w, "DESCRIPTION:"+icalEscaper.Replace(e.Description))
+        }
+        if e.Location != "" {
+            writeLine(w, "LOCATION:"+icalEscaper.Replace(e.Location))
+        }
+        writeLine(w, "END:VEVENT")
+    }
+
+    writeLine(w, "END:VCALENDAR")
+}
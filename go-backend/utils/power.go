@@ -0,0 +1,198 @@
+package utils
+
+import (
+    "sort"
+    "time"
+)
+
+// Starting rating offset of each competition, relative to InitialElo.
+// Cross-competition matches (CEV against domestic teams) move the ratings from there.
+var DefaultLeagueOffsets = map[string]float64{
+    "cev-cl":        100,
+    "vsl":           0,
+    "cev-cup":       -25,
+    "cev-challenge": -100,
+    "1lig":          -250,
+    "2lig":          -450,
+}
+
+// Competition is one league's data as input to the power rankings, with the
+// team ids its league resolved
+type Competition struct {
+    League  string
+    Teams   []TeamStats
+    Matches []Match
+}
+
+type PowerRanking struct {
+    Rank               int      `json:"rank"`
+    Movement           int      `json:"movement"` // places gained since the previous week
+    TeamID             string   `json:"teamId"`
+    Name               string   `json:"name"`
+    Leagues            []string `json:"leagues"`
+    Rating             float64  `json:"rating"`
+    RatingChange       float64  `json:"ratingChange"`
+    Played             int      `json:"played"`
+    Form               []string `json:"form"` // last 5, newest last: "W" or "L"
+    StrengthOfSchedule float64  `json:"strengthOfSchedule"`
+}
+
+type PowerRankings struct {
+    AsOf       string         `json:"asOf"`
+    PreviousAt string         `json:"previousAt"`
+    Rankings   []PowerRanking `json:"rankings"`
+}
+
+type powerTeam struct {
+    name    string
+    leagues []string
+    played  map[string]int // per league, to pick the home competition
+}
+
+// CalculatePowerRankings rates every team of the competitions in one table.
+// Teams start at InitialElo plus the offset of the competition they play most
+// matches in, and the movement compares against the table of a week earlier.
+func CalculatePowerRankings(competitions []Competition, offsets map[string]float64, params EloParams) PowerRankings {
+    teams := make(map[string]*powerTeam)
+    all := make([]Match, 0)
+
+    addTeam := func(id, name, league string) *powerTeam {
+        t, ok := teams[id]
+        if !ok {
+            t = &powerTeam{name: name, played: make(map[string]int)}
+            if reg, ok := Teams().Lookup(id); ok {
+                t.name = reg.Name
+            }
+            teams[id] = t
+        }
+        if _, ok := t.played[league]; !ok {
+            t.leagues = append(t.leagues, league)
+            t.played[league] = 0
+        }
+        return t
+    }
+
+    for _, c := range competitions {
+        for _, t := range c.Teams {
+            addTeam(t.ID, t.Name, c.League)
+        }
+        for _, m := range c.Matches {
+            home := addTeam(m.HomeTeamID, m.HomeTeam, c.League)
+            away := addTeam(m.AwayTeamID, m.AwayTeam, c.League)
+            if m.IsPlayed {
+                home.played[c.League]++
+                away.played[c.League]++
+            }
+        }
+        all = append(all, c.Matches...)
+    }
+
+    seed := func() map[string]float64 {
+        ratings := make(map[string]float64)
+        for id, t := range teams {
+            best, bestPlayed := "", -1
+            for _, league := range t.leagues {
+                if t.played[league] > bestPlayed {
+                    best, bestPlayed = league, t.played[league]
+                }
+            }
+            ratings[id] = InitialElo + offsets[best]
+        }
+        return ratings
+    }
+
+    played := playedMatches(all)
+    asOf := ""
+    if len(played) > 0 {
+        asOf = played[len(played)-1].MatchDate
+    }
+    previousAt := shiftDate(asOf, -7)
+
+    earlier := make([]Match, 0)
+    for _, m := range played {
+        if m.MatchDate <= previousAt {
+            earlier = append(earlier, m)
+        }
+    }
+
+    history := make(map[string][]RatingPoint)
+    ratings := calculateElo(seed(), played, params, history)
+    previous := calculateElo(seed(), earlier, params, nil)
+
+    previousRank := rankByRating(previous)
+    result := PowerRankings{AsOf: asOf, PreviousAt: previousAt, Rankings: make([]PowerRanking, 0, len(teams))}
+
+    for id, t := range teams {
+        points := history[id]
+
+        form := make([]string, 0, 5)
+        for i := max(0, len(points)-5); i < len(points); i++ {
+            if points[i].Won {
+                form = append(form, "W")
+            } else {
+                form = append(form, "L")
+            }
+        }
+
+        sos := 0.0
+        for _, p := range points {
+            sos += ratings[p.OpponentID]
+        }
+        if len(points) > 0 {
+            sos /= float64(len(points))
+        }
+
+        result.Rankings = append(result.Rankings, PowerRanking{
+            TeamID:             id,
+            Name:               t.name,
+            Leagues:            t.leagues,
+            Rating:             ratings[id],
+            RatingChange:       ratings[id] - previous[id],
+            Played:             len(points),
+            Form:               form,
+            StrengthOfSchedule: sos,
+        })
+    }
+
+    sort.Slice(result.Rankings, func(i, j int) bool {
+        if result.Rankings[i].Rating != result.Rankings[j].Rating {
+            return result.Rankings[i].Rating > result.Rankings[j].Rating
+        }
+        return result.Rankings[i].TeamID < result.Rankings[j].TeamID
+    })
+    for i := range result.Rankings {
+        r := &result.Rankings[i]
+        r.Rank = i + 1
+        r.Movement = previousRank[r.TeamID] - r.Rank
+    }
+
+    return result
+}
+
+func rankByRating(ratings map[string]float64) map[string]int {
+    ids := make([]string, 0, len(ratings))
+    for id := range ratings {
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool {
+        if ratings[ids[i]] != ratings[ids[j]] {
+            return ratings[ids[i]] > ratings[ids[j]]
+        }
+        return ids[i] < ids[j]
+    })
+
+    ranks := make(map[string]int, len(ids))
+    for i, id := range ids {
+        ranks[id] = i + 1
+    }
+    return ranks
+}
+
+// shiftDate moves a YYYY-MM-DD date by a number of days
+func shiftDate(date string, days int) string {
+    t, err := time.Parse("2006-01-02", date)
+    if err != nil {
+        return date
+    }
+    return t.AddDate(0, 0, days).Format("2006-01-02")
+}
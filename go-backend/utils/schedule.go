@@ -0,0 +1,142 @@
+package utils
+
+import (
+    "sort"
+)
+
+// ScheduleSplit is the average current rating of a set of opponents
+type ScheduleSplit struct {
+    Matches     int     `json:"matches"`
+    Average     float64 `json:"average"`
+    HomeMatches int     `json:"homeMatches"`
+    HomeAverage float64 `json:"homeAverage"`
+    AwayMatches int     `json:"awayMatches"`
+    AwayAverage float64 `json:"awayAverage"`
+}
+
+// Stretch is a run of consecutive remaining matches
+type Stretch struct {
+    From            string   `json:"from"`
+    To              string   `json:"to"`
+    Matches         int      `json:"matches"`
+    AverageOpponent float64  `json:"averageOpponent"`
+    Opponents       []string `json:"opponents"`
+}
+
+type ScheduleStrength struct {
+    TeamID         string        `json:"teamId"`
+    Name           string        `json:"name"`
+    Rating         float64       `json:"rating"`
+    Played         ScheduleSplit `json:"played"`
+    Remaining      ScheduleSplit `json:"remaining"`
+    HardestStretch *Stretch      `json:"hardestStretch"`
+}
+
+type scheduleEntry struct {
+    date     string
+    opponent string
+    rating   float64
+    home     bool
+}
+
+func split(entries []scheduleEntry) ScheduleSplit {
+    var s ScheduleSplit
+    total, home, away := 0.0, 0.0, 0.0
+    for _, e := range entries {
+        total += e.rating
+        if e.home {
+            s.HomeMatches++
+            home += e.rating
+        } else {
+            s.AwayMatches++
+            away += e.rating
+        }
+    }
+
+    s.Matches = len(entries)
+    if s.Matches > 0 { s.Average = total / float64(s.Matches) }
+    if s.HomeMatches > 0 { s.HomeAverage = home / float64(s.HomeMatches) }
+    if s.AwayMatches > 0 { s.AwayAverage = away / float64(s.AwayMatches) }
+    return s
+}
+
+// hardestStretch finds the window of consecutive remaining matches with the
+// highest average opponent rating
+func hardestStretch(remaining []scheduleEntry, window int) *Stretch {
+    if len(remaining) == 0 || window <= 0 {
+        return nil
+    }
+    if window > len(remaining) {
+        window = len(remaining)
+    }
+
+    best, bestSum := 0, -1.0
+    for start := 0; start+window <= len(remaining); start++ {
+        sum := 0.0
+        for _, e := range remaining[start : start+window] {
+            sum += e.rating
+        }
+        if sum > bestSum {
+            best, bestSum = start, sum
+        }
+    }
+
+    entries := remaining[best : best+window]
+    opponents := make([]string, 0, window)
+    for _, e := range entries {
+        opponents = append(opponents, e.opponent)
+    }
+    return &Stretch{
+        From:            entries[0].date,
+        To:              entries[window-1].date,
+        Matches:         window,
+        AverageOpponent: bestSum / float64(window),
+        Opponents:       opponents,
+    }
+}
+
+// CalculateScheduleStrength rates every team's played and remaining fixture
+// by the current ratings of its opponents, hardest remaining schedule first.
+func CalculateScheduleStrength(teams []TeamStats, matches []Match, params EloParams, window int) []ScheduleStrength {
+    ratings := CalculateEloWithParams(teams, matches, params)
+    rating := func(key string) float64 {
+        if r, ok := ratings[key]; ok { return r }
+        return InitialElo
+    }
+
+    sorted := make([]Match, len(matches))
+    copy(sorted, matches)
+    sort.SliceStable(sorted, func(i, j int) bool {
+        return sorted[i].MatchDate < sorted[j].MatchDate
+    })
+
+    played := make(map[string][]scheduleEntry)
+    remaining := make(map[string][]scheduleEntry)
+    for _, m := range sorted {
+        target := remaining
+        if m.IsPlayed {
+            target = played
+        }
+        home, away := m.HomeKey(), m.AwayKey()
+        target[home] = append(target[home], scheduleEntry{m.MatchDate, m.AwayTeam, rating(away), true})
+        target[away] = append(target[away], scheduleEntry{m.MatchDate, m.HomeTeam, rating(home), false})
+    }
+
+    result := make([]ScheduleStrength, 0, len(teams))
+    for _, t := range teams {
+        key := t.Key()
+        result = append(result, ScheduleStrength{
+            TeamID:         t.ID,
+            Name:           t.Name,
+            Rating:         rating(key),
+            Played:         split(played[key]),
+            Remaining:      split(remaining[key]),
+            HardestStretch: hardestStretch(remaining[key], window),
+        })
+    }
+
+    sort.SliceStable(result, func(i, j int) bool {
+        return result[i].Remaining.Average > result[j].Remaining.Average
+    })
+    return result
+}
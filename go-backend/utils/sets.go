@@ -0,0 +1,115 @@
+package utils
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// SetScore is the rally points of one set
+type SetScore struct {
+    Home int `json:"home"`
+    Away int `json:"away"`
+}
+
+var setScorePattern = regexp.MustCompile(`(\d+)\s*-\s*(\d+)`)
+
+// ParseSets reads set scores in any of the formats the data files use:
+// "(25-19) (25-20)", "(25-17, 25-17)" or "25-19, 25-20". Trailing notes
+// such as "- Hükmen" are ignored.
+func ParseSets(s string) []SetScore {
+    var sets []SetScore
+    for _, m := range setScorePattern.FindAllStringSubmatch(s, -1) {
+        home, _ := strconv.Atoi(m[1])
+        away, _ := strconv.Atoi(m[2])
+        sets = append(sets, SetScore{home, away})
+    }
+    return sets
+}
+
+// FormatSets writes set scores the way the data files do, "(25-20) (25-18)"
+func FormatSets(sets []SetScore) string {
+    parts := make([]string, len(sets))
+    for i, s := range sets {
+        parts[i] = fmt.Sprintf("(%d-%d)", s.Home, s.Away)
+    }
+    return strings.Join(parts, " ")
+}
+
+// Best-of-five set rules
+const (
+    SetPoints      = 25 // points to win a set
+    TieBreakPoints = 15 // points to win the fifth set, or a golden set
+    SetMargin      = 2  // lead needed to win a set
+    SetsToWin      = 3
+)
+
+// CheckResult returns why home-away is not a finished best-of-five result
+func CheckResult(home, away int) error {
+    if home < 0 || away < 0 || home > SetsToWin || away > SetsToWin || (home == SetsToWin) == (away == SetsToWin) {
+        return fmt.Errorf("%d-%d is not a best-of-five result", home, away)
+    }
+    return nil
+}
+
+// CheckSet returns why a set score is impossible, nil when it is valid. The
+// winner reaches the target with a two point lead; past the target the set
+// only ends once that lead exists, so the margin is then exactly two.
+func CheckSet(s SetScore, tieBreak bool) error {
+    target := SetPoints
+    if tieBreak { target = TieBreakPoints }
+
+    win, lose := s.Home, s.Away
+    if lose > win { win, lose = lose, win }
+    switch {
+    case lose < 0:
+        return fmt.Errorf("set %d-%d has negative points", s.Home, s.Away)
+    case win < target:
+        return fmt.Errorf("set %d-%d ended before %d points", s.Home, s.Away, target)
+    case win-lose < SetMargin:
+        return fmt.Errorf("set %d-%d was won without a %d point lead", s.Home, s.Away, SetMargin)
+    case win > target && win-lose != SetMargin:
+        return fmt.Errorf("set %d-%d went past %d without ending at a %d point lead", s.Home, s.Away, target, SetMargin)
+    }
+    return nil
+}
+
+// CheckSets returns why the set scores of a finished match cannot give the
+// result home-away, nil when they are consistent: every set valid, the fifth
+// one a tie-break, and the sets won adding up to the result with no set
+// played after a side reached three.
+func CheckSets(sets []SetScore, home, away int) error {
+    if err := CheckResult(home, away); err != nil {
+        return err
+    }
+    if len(sets) != home+away {
+        return fmt.Errorf("%d sets for a %d-%d result", len(sets), home, away)
+    }
+
+    won := [2]int{}
+    for i, s := range sets {
+        if won[0] == SetsToWin || won[1] == SetsToWin {
+            return fmt.Errorf("set %d was played after the match was decided", i+1)
+        }
+        if err := CheckSet(s, i == 4); err != nil { // the fifth set is the tie-break
+            return fmt.Errorf("set %d: %w", i+1, err)
+        }
+        if s.Home > s.Away { won[0]++ } else { won[1]++ }
+    }
+    if won[0] != home || won[1] != away {
+        return fmt.Errorf("sets won %d-%d do not match the result %d-%d", won[0], won[1], home, away)
+    }
+    return nil
+}
+
+// SplitSets separates set scores into the home and away points per set, as
+// the home_sets and away_sets columns store them
+func SplitSets(sets []SetScore) ([]int, []int) {
+    home, away := make([]int, 0, len(sets)), make([]int, 0, len(sets))
+    for _, s := range sets {
+        home = append(home, s.Home)
+        away = append(away, s.Away)
+    }
+    return home, away
+}
@@ -0,0 +1,49 @@
+package utils
+
+import "testing"
+
+func TestCheckSet(t *testing.T) {
+    for _, tc := range []struct {
+        set      SetScore
+        tieBreak bool
+        valid    bool
+    }{
+        {SetScore{25, 20}, false, true},
+        {SetScore{26, 24}, false, true},
+        {SetScore{28, 30}, false, true},
+        {SetScore{25, 24}, false, false}, // no two point lead
+        {SetScore{27, 24}, false, false}, // would have ended at 26-24
+        {SetScore{24, 20}, false, false},
+        {SetScore{15, 13}, true, true},
+        {SetScore{17, 15}, true, true},
+        {SetScore{25, 23}, true, true}, // long tie-breaks happen
+        {SetScore{26, 24}, true, true},
+        {SetScore{16, 13}, true, false}, // would have ended at 15-13
+        {SetScore{15, 13}, false, false},
+    } {
+        if err := CheckSet(tc.set, tc.tieBreak); (err == nil) != tc.valid {
+            t.Errorf("CheckSet(%v, tie-break %t) = %v, want valid %t", tc.set, tc.tieBreak, err, tc.valid)
+        }
+    }
+}
+
+func TestCheckSets(t *testing.T) {
+    for _, tc := range []struct {
+        sets       string
+        home, away int
+        valid      bool
+    }{
+        {"(25-20) (26-24) (30-28)", 3, 0, true},
+        {"(25-20) (20-25) (25-22) (22-25) (15-13)", 3, 2, true},
+        {"(25-20) (20-25) (25-22) (22-25) (26-24)", 3, 2, true},
+        {"(25-20) (20-25) (25-22) (22-25) (25-20)", 3, 2, false}, // the fifth set is a tie-break
+        {"(25-20) (25-22) (25-18) (20-25)", 3, 1, false},          // a set after 3-0
+        {"(25-20) (25-22) (25-18) (20-25)", 3, 0, false},
+        {"(25-20) (20-25) (25-22) (25-23)", 1, 3, false},          // sets won 3-1 for 1-3
+        {"(25-20) (25-22)", 2, 0, false},                          // not finished
+    } {
+        if err := CheckSets(ParseSets(tc.sets), tc.home, tc.away); (err == nil) != tc.valid {
+            t.Errorf("CheckSets(%s, %d-%d) = %v, want valid %t", tc.sets, tc.home, tc.away, err, tc.valid)
+        }
+    }
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+    "math/rand"
+    "sort"
+)
+
+type MatchOutcome struct {
+    HomeSets   int
+    AwaySets   int
+    HomePoints int
+    AwayPoints int
+    HomeWin    bool
+}
+
+// SimulateMatch draws a result from the Elo win probability. The losing
+// side's sets follow how lopsided the matchup is.
+func SimulateMatch(rng *rand.Rand, params EloParams, homeElo, awayElo float64) MatchOutcome {
+    expectedHome := params.Expected(homeElo, awayElo)
+    homeWin := rng.Float64() < expectedHome
+
+    dominance := expectedHome
+    if !homeWin { dominance = 1.0 - expectedHome }
+
+    loserSets := 2
+    r2 := rng.Float64()
+    if dominance > 0.8 {
+        if r2 < 0.7 { loserSets = 0 } else { loserSets = 1 }
+    } else if dominance > 0.6 {
+        if r2 < 0.5 { loserSets = 1 } else { loserSets = 2 }
+    }
+
+    hSets, aSets := 3, loserSets
+    if !homeWin { hSets, aSets = loserSets, 3 }
+
+    hPoints, aPoints := MatchPoints(hSets, aSets)
+    return MatchOutcome{hSets, aSets, hPoints, aPoints, homeWin}
+}
+
+// Simulation counts how often each team finished in each position
+type Simulation struct {
+    Runs  int
+    Teams int
+    Ranks map[string][]int // team key -> count per rank, index 0 is first place
+}
+
+// SimulateSeason plays the unplayed matches runs times from the current
+// table and ratings and records every team's final rank. Teams are
+// ordered like the league tables (CompareStandings): wins, points, set
+// ratio, then rally point ratio.
+func SimulateSeason(teams []TeamStats, matches []Match, params EloParams, runs int) *Simulation {
+    eloMap := CalculateEloWithParams(teams, matches, params)
+
+    remaining := make([]Match, 0)
+    for _, m := range matches {
+        if !m.IsPlayed {
+            remaining = append(remaining, m)
+        }
+    }
+
+    sim := &Simulation{Runs: runs, Teams: len(teams), Ranks: make(map[string][]int, len(teams))}
+    for _, t := range teams {
+        sim.Ranks[t.Key()] = make([]int, len(teams))
+    }
+
+    rng := rand.New(rand.NewSource(rand.Int63()))
+    simList := make([]TeamStats, len(teams))
+    index := make(map[string]int, len(teams))
+
+    for i := 0; i < runs; i++ {
+        copy(simList, teams)
+        for j, t := range simList {
+            index[t.Key()] = j
+        }
+
+        for _, m := range remaining {
+            hKey, aKey := m.HomeKey(), m.AwayKey()
+
+            hElo := eloMap[hKey]
+            if hElo == 0 { hElo = InitialElo }
+            aElo := eloMap[aKey]
+            if aElo == 0 { aElo = InitialElo }
+
+            res := SimulateMatch(rng, params, hElo, aElo)
+
+            if j, ok := index[hKey]; ok {
+                h := &simList[j]
+                h.Played++
+                if res.HomeWin { h.Wins++ }
+                h.Points += res.HomePoints
+                h.SetsWon += res.HomeSets
+                h.SetsLost += res.AwaySets
+            }
+            if j, ok := index[aKey]; ok {
+                a := &simList[j]
+                a.Played++
+                if !res.HomeWin { a.Wins++ }
+                a.Points += res.AwayPoints
+                a.SetsWon += res.AwaySets
+                a.SetsLost += res.HomeSets
+            }
+        }
+
+        // Same order as the league tables
+        sort.Slice(simList, func(x, y int) bool {
+            return CompareStandings(simList[x].Order(), simList[y].Order()) < 0
+        })
+
+        for rank, t := range simList {
+            sim.Ranks[t.Key()][rank]++
+        }
+    }
+    return sim
+}
+
+type SimulationOdds struct {
+    BestRank     int     `json:"bestRank"`
+    WorstRank    int     `json:"worstRank"`
+    AverageRank  float64 `json:"averageRank"`
+    Championship float64 `json:"championshipProbability"` // percentages
+    Playoff      float64 `json:"playoffProbability"`
+    Relegation   float64 `json:"relegationProbability"`
+}
+
+// Odds summarizes one team's finishing positions. ok is false for teams
+// that were not part of the simulation.
+func (s *Simulation) Odds(key string, playoffSpots, relegationSpots int) (SimulationOdds, bool) {
+    counts, ok := s.Ranks[key]
+    if !ok || s.Runs == 0 {
+        return SimulationOdds{}, false
+    }
+
+    var odds SimulationOdds
+    var champ, playoff, relegation, rankSum int
+    for i, n := range counts {
+        if n == 0 { continue }
+        rank := i + 1
+        if odds.BestRank == 0 { odds.BestRank = rank }
+        odds.WorstRank = rank
+        rankSum += rank * n
+        if rank == 1 { champ += n }
+        if rank <= playoffSpots { playoff += n }
+        if rank > s.Teams-relegationSpots { relegation += n }
+    }
+
+    pct := func(n int) float64 { return float64(n) * 100 / float64(s.Runs) }
+    odds.AverageRank = float64(rankSum) / float64(s.Runs)
+    odds.Championship = pct(champ)
+    odds.Playoff = pct(playoff)
+    odds.Relegation = pct(relegation)
+    return odds, true
+}
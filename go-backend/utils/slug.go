@@ -0,0 +1,37 @@
+package utils
+
+import (
+    "strings"
+)
+
+var turkishReplacer = strings.NewReplacer(
+    "ş", "s", "Ş", "S",
+    "ğ", "g", "Ğ", "G",
+    "ü", "u", "Ü", "U",
+    "ö", "o", "Ö", "O",
+    "ç", "c", "Ç", "C",
+    "ı", "i", "İ", "I",
+)
+
+// Slugify generates the same URL slug as generateTeamSlug in the frontend
+// (app/lib/core/teamSlug.ts), e.g. "Fenerbahçe Medicana" -> "fenerbahce-medicana"
+func Slugify(name string) string {
+    normalized := strings.ToLower(turkishReplacer.Replace(name))
+
+    var b strings.Builder
+    lastHyphen := true // trims leading hyphens
+    for _, r := range strings.TrimSpace(normalized) {
+        switch {
+        case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+            b.WriteRune(r)
+            lastHyphen = false
+        case r == '-' || r == ' ' || r == '\t' || r == '\n':
+            if !lastHyphen {
+                b.WriteByte('-')
+                lastHyphen = true
+            }
+        }
+    }
+
+    return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,39 @@
+package utils
+
+// TableOrder is what decides the order of a league table
+type TableOrder struct {
+    Wins       int
+    Points     int
+    SetRatio   float64
+    PointRatio float64 // rally points, 0 where they are unknown
+}
+
+// Ratio is won/lost, with won itself when nothing was lost, like the frontend
+func Ratio(won, lost int) float64 {
+    if lost == 0 { return float64(won) }
+    return float64(won) / float64(lost)
+}
+
+// CompareStandings orders two table rows by wins, points, set ratio, then
+// rally point ratio. It is negative when a ranks above b and 0 on a tie,
+// which the league table breaks by the points taken between the two teams.
+func CompareStandings(a, b TableOrder) int {
+    switch {
+    case a.Wins != b.Wins:
+        return b.Wins - a.Wins
+    case a.Points != b.Points:
+        return b.Points - a.Points
+    case a.SetRatio != b.SetRatio:
+        if a.SetRatio > b.SetRatio { return -1 }
+        return 1
+    case a.PointRatio != b.PointRatio:
+        if a.PointRatio > b.PointRatio { return -1 }
+        return 1
+    }
+    return 0
+}
+
+// Order is the team's place in the table, without rally points
+func (t TeamStats) Order() TableOrder {
+    return TableOrder{Wins: t.Wins, Points: t.Points, SetRatio: Ratio(t.SetsWon, t.SetsLost)}
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+    "encoding/json"
+    "os"
+    "strings"
+    "unicode"
+
+    "golang.org/x/text/unicode/norm"
+)
+
+// RegisteredTeam is a canonical team with the names it appears under
+type RegisteredTeam struct {
+    ID      string   `json:"id"`
+    Name    string   `json:"name"`
+    Country string   `json:"country,omitempty"`
+    Leagues []string `json:"leagues,omitempty"` // team scopes where the aliases apply, empty means everywhere
+    Aliases []string `json:"aliases"`
+}
+
+type TeamRegistry struct {
+    Teams []RegisteredTeam `json:"teams"`
+
+    byID     map[string]*RegisteredTeam
+    global   map[string]string            // team key -> id
+    byLeague map[string]map[string]string // team scope -> team key -> id
+}
+
+const DefaultTeamRegistryPath = "config/teams.json"
+
+// Loaded at startup, read-only afterwards
+var teamRegistry = NewTeamRegistry(nil)
+
+func NewTeamRegistry(teams []RegisteredTeam) *TeamRegistry {
+    r := &TeamRegistry{
+        Teams:    teams,
+        byID:     make(map[string]*RegisteredTeam),
+        global:   make(map[string]string),
+        byLeague: make(map[string]map[string]string),
+    }
+
+    for i := range r.Teams {
+        t := &r.Teams[i]
+        r.byID[t.ID] = t
+
+        names := append([]string{t.Name}, t.Aliases...)
+        for _, name := range names {
+            key := TeamKey(name)
+            if len(t.Leagues) == 0 {
+                r.global[key] = t.ID
+                continue
+            }
+            for _, league := range t.Leagues {
+                if r.byLeague[league] == nil {
+                    r.byLeague[league] = make(map[string]string)
+                }
+                r.byLeague[league][key] = t.ID
+            }
+        }
+    }
+    return r
+}
+
+// LoadTeamRegistry reads the canonical teams and their aliases
+func LoadTeamRegistry(path string) error {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+
+    var file TeamRegistry
+    if err := json.Unmarshal(content, &file); err != nil {
+        return err
+    }
+
+    teamRegistry = NewTeamRegistry(file.Teams)
+    return nil
+}
+
+func Teams() *TeamRegistry {
+    return teamRegistry
+}
+
+// Resolve returns the stable id of a team name as written in a league's data,
+// under the league's team scope (league.TeamScope). Names without a registry
+// entry get an id scoped to their competition, so that a reserve team in
+// 1. Lig never merges with the first team in VSL.
+func (r *TeamRegistry) Resolve(scope, name string) string {
+    key := TeamKey(name)
+    if id, ok := r.byLeague[scope][key]; ok {
+        return id
+    }
+    if id, ok := r.global[key]; ok {
+        return id
+    }
+
+    slug := Slugify(key)
+    if scope != "" {
+        return slug + "-" + scope
+    }
+    return slug
+}
+
+// Lookup returns the registry entry of an id, if the team is registered
+func (r *TeamRegistry) Lookup(id string) (RegisteredTeam, bool) {
+    if t, ok := r.byID[id]; ok {
+        return *t, true
+    }
+    return RegisteredTeam{}, false
+}
+
+// ResolveTeam resolves a name with the registry loaded at startup
+func ResolveTeam(scope, name string) string {
+    return teamRegistry.Resolve(scope, name)
+}
+
+var teamKeyFolds = strings.NewReplacer(
+    "ı", "i", "İ", "I",
+    "ł", "l", "Ł", "L",
+    "ø", "o", "Ø", "O",
+    "đ", "d", "Đ", "D",
+    "ß", "ss",
+)
+
+// Country codes CEV appends to team names, e.g. "AEK ATHENS GRE"
+var cevCountryCodes = map[string]bool{
+    "ALB": true, "AUT": true, "AZE": true, "BEL": true, "BIH": true, "BUL": true, "CRO": true,
+    "CYP": true, "CZE": true, "DEN": true, "ESP": true, "EST": true, "FAR": true, "FIN": true,
+    "FRA": true, "GER": true, "GRE": true, "HUN": true, "ISR": true, "ITA": true, "KOS": true,
+    "LAT": true, "LTU": true, "LUX": true, "MKD": true, "MNE": true, "NED": true, "NOR": true,
+    "POL": true, "POR": true, "ROU": true, "SLO": true, "SRB": true, "SUI": true, "SVK": true,
+    "SWE": true, "TUR": true, "UKR": true,
+}
+
+// TeamKey normalizes a team name for matching: diacritics (including the
+// Turkish dotless i) are folded, case and punctuation are ignored and a
+// trailing CEV country code is dropped.
+func TeamKey(name string) string {
+    folded := norm.NFD.String(teamKeyFolds.Replace(name))
+
+    var b strings.Builder
+    space := true
+    for _, r := range folded {
+        switch {
+        case unicode.Is(unicode.Mn, r):
+            // combining mark left over from NFD
+        case unicode.IsLetter(r) || unicode.IsDigit(r):
+            b.WriteRune(unicode.ToUpper(r))
+            space = false
+        default:
+            if !space {
+                b.WriteByte(' ')
+                space = true
+            }
+        }
+    }
+
+    key := strings.TrimSpace(b.String())
+    if i := strings.LastIndexByte(key, ' '); i > 0 && cevCountryCodes[key[i+1:]] {
+        key = key[:i]
+    }
+    return key
+}
+
+// ResolveTeamIDs fills in the stable ids of teams and matches that have none,
+// resolving names under a league's team scope
+func ResolveTeamIDs(scope string, teams []TeamStats, matches []Match) {
+    for i := range teams {
+        if teams[i].ID == "" {
+            teams[i].ID = ResolveTeam(scope, teams[i].Name)
+        }
+    }
+    for i := range matches {
+        if matches[i].HomeTeamID == "" {
+            matches[i].HomeTeamID = ResolveTeam(scope, matches[i].HomeTeam)
+        }
+        if matches[i].AwayTeamID == "" {
+            matches[i].AwayTeamID = ResolveTeam(scope, matches[i].AwayTeam)
+        }
+    }
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+    "log"
+    "time"
+    _ "time/tzdata" // Europe/Istanbul on hosts without a zoneinfo database
+)
+
+// TurkeyTime is the zone of fixture dates, calendars and job schedules.
+// Turkey has stayed on UTC+3 since 2016, which is the fallback.
+var TurkeyTime = loadTurkeyTime()
+
+func loadTurkeyTime() *time.Location {
+    loc, err := time.LoadLocation("Europe/Istanbul")
+    if err != nil {
+        log.Printf("Time zone Europe/Istanbul not found, using UTC+3: %v", err)
+        return time.FixedZone("Europe/Istanbul", 3*60*60)
+    }
+    return loc
+}